@@ -0,0 +1,307 @@
+package csv
+
+import (
+	"bytes"
+	gocsv "encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var errDuplicateColumn = errors.New("duplicate column in header")
+
+// DecodeOptions controls how CSV data is read.
+type DecodeOptions struct {
+	// Comma is the field delimiter. It defaults to ',' when zero; use '\t'
+	// for TSV.
+	Comma rune
+	// Comment, when not zero, starts comment lines, which are skipped.
+	Comment rune
+	// LazyQuotes allows quotes in unquoted fields and unescaped quotes in
+	// quoted fields.
+	LazyQuotes bool
+	// Header reads the first record as column names and returns each
+	// following record as a table keyed by them, instead of as a list.
+	Header bool
+}
+
+// EncodeOptions controls how CSV data is written.
+type EncodeOptions struct {
+	// Comma is the field delimiter. It defaults to ',' when zero.
+	Comma rune
+	// Columns, when set, lists the keys of the row tables to write, in order.
+	// Otherwise rows are lists of fields.
+	Columns []string
+	// Header writes Columns as the first record.
+	Header bool
+	// UseCRLF ends records with \r\n instead of \n.
+	UseCRLF bool
+}
+
+// Decode reads the CSV encoded data and returns its records as a list of
+// lists of strings, or of tables keyed by column name if opts.Header is set.
+// All records must have as many fields as the first one.
+func Decode(L *lua.LState, data []byte, opts DecodeOptions) (*lua.LTable, error) {
+	reader := gocsv.NewReader(bytes.NewReader(data))
+	reader.Comment = opts.Comment
+	reader.LazyQuotes = opts.LazyQuotes
+
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Header {
+		list := L.CreateTable(len(records), 0)
+		for _, record := range records {
+			list.Append(fieldList(L, record))
+		}
+
+		return list, nil
+	}
+
+	if len(records) == 0 {
+		return L.NewTable(), nil
+	}
+
+	header := records[0]
+
+	seen := make(map[string]bool, len(header))
+	for _, name := range header {
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %q", errDuplicateColumn, name)
+		}
+
+		seen[name] = true
+	}
+
+	list := L.CreateTable(len(records)-1, 0)
+
+	for _, record := range records[1:] {
+		row := L.CreateTable(0, len(header))
+		for i, name := range header {
+			row.RawSetString(name, lua.LString(record[i]))
+		}
+
+		list.Append(row)
+	}
+
+	return list, nil
+}
+
+func fieldList(L *lua.LState, record []string) *lua.LTable {
+	fields := L.CreateTable(len(record), 0)
+	for _, field := range record {
+		fields.Append(lua.LString(field))
+	}
+
+	return fields
+}
+
+// Encode returns the CSV encoding of rows, a list of lists of fields, or of
+// tables holding the fields under the names in opts.Columns. Strings are
+// written as is, numbers without exponent when integral, booleans as true or
+// false and missing fields as empty strings.
+func Encode(rows *lua.LTable, opts EncodeOptions) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writer := gocsv.NewWriter(&buf)
+	writer.UseCRLF = opts.UseCRLF
+
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+
+	if opts.Header && len(opts.Columns) > 0 {
+		err := writer.Write(opts.Columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 1; i <= rows.Len(); i++ {
+		row, ok := rows.RawGetInt(i).(*lua.LTable)
+		if !ok {
+			return nil, fmt.Errorf("row %d: expected a table, got %s", i, rows.RawGetInt(i).Type())
+		}
+
+		record, err := encodeRecord(row, opts.Columns)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+
+		err = writer.Write(record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+
+	err := writer.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeRecord(row *lua.LTable, columns []string) ([]string, error) {
+	if len(columns) == 0 {
+		record := make([]string, row.Len())
+
+		for i := range record {
+			field, err := encodeField(row.RawGetInt(i + 1))
+			if err != nil {
+				return nil, fmt.Errorf("field %d: %w", i+1, err)
+			}
+
+			record[i] = field
+		}
+
+		return record, nil
+	}
+
+	record := make([]string, len(columns))
+
+	for i, column := range columns {
+		field, err := encodeField(row.RawGetString(column))
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", column, err)
+		}
+
+		record[i] = field
+	}
+
+	return record, nil
+}
+
+func encodeField(value lua.LValue) (string, error) {
+	switch converted := value.(type) {
+	case *lua.LNilType:
+		return "", nil
+	case lua.LString:
+		return string(converted), nil
+	case lua.LBool:
+		return strconv.FormatBool(bool(converted)), nil
+	case lua.LNumber:
+		f := float64(converted)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("cannot encode number %v to CSV", f)
+		}
+
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("cannot encode %s to CSV", value.Type())
+	}
+}
+
+// checkRune reads the single character option name of the options table tbl,
+// or returns zero if it is not set.
+func checkRune(L *lua.LState, tbl *lua.LTable, name string) rune {
+	value := tbl.RawGetString(name)
+	if value == lua.LNil {
+		return 0
+	}
+
+	str, ok := value.(lua.LString)
+
+	r, size := utf8.DecodeRuneInString(string(str))
+	if !ok || r == utf8.RuneError || size != len(str) {
+		L.ArgError(2, name+" must be a single character")
+	}
+
+	return r
+}
+
+func apiDecode(L *lua.LState) int {
+	str := L.CheckString(1)
+
+	var opts DecodeOptions
+	if tbl := L.OptTable(2, nil); tbl != nil {
+		opts.Comma = checkRune(L, tbl, "delimiter")
+		opts.Comment = checkRune(L, tbl, "comment")
+		opts.LazyQuotes = lua.LVAsBool(tbl.RawGetString("lazyQuotes"))
+		opts.Header = lua.LVAsBool(tbl.RawGetString("header"))
+	}
+
+	rows, err := Decode(L, []byte(str), opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(rows)
+
+	return 1
+}
+
+func apiEncode(L *lua.LState) int {
+	rows := L.CheckTable(1)
+
+	opts := EncodeOptions{Header: true}
+
+	if tbl := L.OptTable(2, nil); tbl != nil {
+		opts.Comma = checkRune(L, tbl, "delimiter")
+		opts.UseCRLF = lua.LVAsBool(tbl.RawGetString("useCRLF"))
+
+		if header, ok := tbl.RawGetString("header").(lua.LBool); ok {
+			opts.Header = bool(header)
+		}
+
+		if columns, ok := tbl.RawGetString("columns").(*lua.LTable); ok {
+			for i := 1; i <= columns.Len(); i++ {
+				name, ok := columns.RawGetInt(i).(lua.LString)
+				if !ok {
+					L.ArgError(2, "columns must be a list of strings")
+				}
+
+				opts.Columns = append(opts.Columns, string(name))
+			}
+		}
+	}
+
+	data, err := Encode(rows, opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LString(string(data)))
+
+	return 1
+}
+
+var api = map[string]lua.LGFunction{
+	"decode": apiDecode,
+	"encode": apiEncode,
+}
+
+// Loader is the module loader function.
+func Loader(L *lua.LState) int {
+	t := L.NewTable()
+	L.SetFuncs(t, api)
+	L.Push(t)
+
+	return 1
+}
+
+// Preload adds csv to the given Lua state's package.preload table. After it
+// has been preloaded, it can be loaded using require:
+//
+//	local csv = require("csv")
+func Preload(L *lua.LState) {
+	L.PreloadModule("csv", Loader)
+}
@@ -0,0 +1,105 @@
+package csv_test
+
+import (
+	"testing"
+
+	luacsv "github.com/projectsveltos/lua-utils/glua-csv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestCSVLua(t *testing.T) {
+	const str = `
+	local csv = require("csv")
+	assert(type(csv.decode) == "function")
+	assert(type(csv.encode) == "function")
+
+	-- Test parsing lists
+	local rows = csv.decode('a,"b,c",d\n1,"say ""hi""","multi\nline"\n')
+	assert(#rows == 2)
+	assert(rows[1][2] == "b,c")
+	assert(rows[2][2] == 'say "hi"')
+	assert(rows[2][3] == "multi\nline")
+
+	-- Test the header option
+	local tenants = csv.decode([[
+# tenant map
+name,namespace,cidr
+acme,tenant-acme,10.0.0.0/24
+"globex, inc",tenant-globex,10.0.1.0/24
+]], {header = true, comment = "#"})
+	assert(#tenants == 2)
+	assert(tenants[1].namespace == "tenant-acme")
+	assert(tenants[2].name == "globex, inc")
+	assert(#csv.decode("", {header = true}) == 0)
+
+	-- Test TSV and lazy quotes
+	local rows = csv.decode('10.0.0.1\tweb "frontend"\n', {delimiter = "\t", lazyQuotes = true})
+	assert(rows[1][2] == 'web "frontend"')
+
+	-- Test encoding with columns
+	local out = csv.encode(tenants, {columns = {"namespace", "name"}})
+	assert(out == 'namespace,name\ntenant-acme,acme\ntenant-globex,"globex, inc"\n', out)
+
+	local out = csv.encode({{port = 80, open = true}, {port = 443}}, {columns = {"port", "open"}, header = false, useCRLF = true})
+	assert(out == "80,true\r\n443,\r\n", out)
+
+	-- Test encoding lists and the round trip
+	local rows = {{"a", "b,c"}, {1.5, 'say "hi"'}}
+	local out = csv.encode(rows, {delimiter = ";"})
+	assert(out == 'a;b,c\n1.5;"say ""hi"""\n', out)
+	local again = csv.decode(out, {delimiter = ";"})
+	assert(again[2][2] == 'say "hi"')
+
+	-- Test errors
+	local rows, err = csv.decode("a,b\nc\n")
+	assert(rows == nil)
+	assert(err == "record on line 2: wrong number of fields", err)
+
+	local rows, err = csv.decode("a,a\n1,2\n", {header = true})
+	assert(rows == nil)
+	assert(err == 'duplicate column in header: "a"', err)
+
+	local out, err = csv.encode({{"a"}, {{}}})
+	assert(out == nil)
+	assert(err == "row 2: field 1: cannot encode table to CSV", err)
+
+	local out, err = csv.encode({{"a"}, "b"})
+	assert(out == nil)
+	assert(err == "row 2: expected a table, got string", err)
+
+	local ok, err = pcall(csv.decode, "a", {delimiter = ",,"})
+	assert(not ok)
+	assert(string.find(err, "delimiter must be a single character"), err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luacsv.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	require.NoError(t, L.DoString(`rows = {{name = "allow", cidr = "10.0.0.0/8", weight = 1e21}}`))
+
+	rows := L.GetGlobal("rows").(*lua.LTable)
+
+	data, err := luacsv.Encode(rows, luacsv.EncodeOptions{Comma: '\t', Columns: []string{"cidr", "name", "weight"}, Header: true})
+	require.NoError(t, err)
+	assert.Equal(t, "cidr\tname\tweight\n10.0.0.0/8\tallow\t1000000000000000000000\n", string(data))
+
+	decoded, err := luacsv.Decode(L, data, luacsv.DecodeOptions{Comma: '\t', Header: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, decoded.Len())
+	assert.Equal(t, lua.LString("allow"), decoded.RawGetInt(1).(*lua.LTable).RawGetString("name"))
+
+	_, err = luacsv.Decode(L, []byte("a\n"), luacsv.DecodeOptions{Comma: '"'})
+	require.Error(t, err)
+}
@@ -0,0 +1,57 @@
+// Package csv is a CSV and TSV parser and generator for gopher-lua, based on
+// encoding/csv.
+//
+// # Documentation
+//
+// The following functions are exposed by the library:
+//
+//	decode(string[, options]):
+//	                  Parses a CSV string and returns its records as a list of
+//	                  lists of strings. Quoted fields may hold delimiters,
+//	                  quotes and newlines. All records must have as many fields
+//	                  as the first one. Returns nil and an error string naming
+//	                  the line, such as "record on line 3: wrong number of
+//	                  fields", if the string could not be parsed. See below for
+//	                  options.
+//	encode(rows[, options]):
+//	                  Generates a CSV string from rows, a list of lists of
+//	                  fields or, with the columns option, of tables holding
+//	                  the fields by column name. Strings are written as is,
+//	                  numbers without exponent, booleans as true or false and
+//	                  nil as an empty field. Returns nil and an error string
+//	                  naming the row if a field could not be encoded.
+//
+// The following decode options are supported:
+//
+//	delimiter:        The field delimiter, a single character. Defaults to ",";
+//	                  use "\t" for TSV.
+//	comment:          A character starting comment lines, which are skipped.
+//	                  Comments are not recognised by default.
+//	lazyQuotes:       Whether quotes may appear in unquoted fields and quotes in
+//	                  quoted fields may be left unescaped. Defaults to false.
+//	header:           Whether the first record holds column names, in which case
+//	                  the following records are returned as tables keyed by
+//	                  them. Duplicate column names are an error. Defaults to
+//	                  false.
+//
+// The following encode options are supported:
+//
+//	delimiter:        The field delimiter, a single character. Defaults to ",".
+//	columns:          The list of column names to write from each row table, in
+//	                  order. Rows are lists of fields when it is not set.
+//	header:           Whether columns are written as the first record. Defaults
+//	                  to true.
+//	useCRLF:          Whether records end with "\r\n" instead of "\n". Defaults
+//	                  to false.
+//
+// # Example
+//
+// Below is an example usage of the library:
+//
+//	import (
+//	    luacsv "github.com/projectsveltos/lua-utils/glua-csv"
+//	)
+//
+//	L := lua.NewState()
+//	luacsv.Preload(L)
+package csv // import "github.com/projectsveltos/lua-utils/glua-csv"
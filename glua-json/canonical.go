@@ -0,0 +1,215 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Canonical returns the RFC 8785 JSON Canonicalization Scheme (JCS) encoding
+// of value: no whitespace, object keys sorted by their UTF-16 code units,
+// numbers formatted as ECMAScript does and strings escaped minimally. Equal
+// values always produce identical bytes, which makes the result suitable for
+// hashing. Numbers are IEEE 754 doubles in JCS, so large integers decoded with
+// UseNumber lose their exact digits.
+func Canonical(value lua.LValue) ([]byte, error) {
+	return CanonicalWithOptions(value, EncodeOptions{})
+}
+
+// CanonicalWithOptions is like Canonical, encoding value according to opts.
+// Prefix, Indent, EscapeHTML and SortKeys are ignored.
+func CanonicalWithOptions(value lua.LValue, opts EncodeOptions) ([]byte, error) {
+	opts.Prefix = ""
+	opts.Indent = ""
+	opts.EscapeHTML = false
+
+	data, err := EncodeWithOptions(value, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var goValue any
+
+	err = decoder.Decode(&goValue)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = writeCanonical(&buf, goValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, value any) error {
+	switch converted := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(converted))
+	case string:
+		writeCanonicalString(buf, converted)
+	case json.Number:
+		f, err := strconv.ParseFloat(converted.String(), 64)
+		if err != nil {
+			return fmt.Errorf("cannot encode number %s in canonical JSON", converted)
+		}
+
+		buf.WriteString(canonicalNumber(f))
+	case []any:
+		buf.WriteByte('[')
+
+		for i, item := range converted {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			err := writeCanonical(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(converted))
+		for key := range converted {
+			keys = append(keys, key)
+		}
+
+		slices.SortFunc(keys, compareUTF16)
+
+		buf.WriteByte('{')
+
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			writeCanonicalString(buf, key)
+			buf.WriteByte(':')
+
+			err := writeCanonical(buf, converted[key])
+			if err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("cannot encode %T in canonical JSON", value)
+	}
+
+	return nil
+}
+
+// compareUTF16 orders a and b by their UTF-16 code units, as JCS sorts keys.
+func compareUTF16(a, b string) int {
+	return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+}
+
+// writeCanonicalString writes str quoted, escaping only the quotation mark,
+// the backslash and control characters. Encode has already replaced invalid
+// UTF-8 with U+FFFD.
+func writeCanonicalString(buf *bytes.Buffer, str string) {
+	buf.WriteByte('"')
+
+	for _, r := range str {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+
+	buf.WriteByte('"')
+}
+
+// canonicalNumber formats the finite number f as ECMAScript's
+// Number.prototype.toString does.
+func canonicalNumber(f float64) string {
+	if f == 0 {
+		return "0"
+	}
+
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+
+	// The shortest digits that round-trip, and the position n of the decimal
+	// point relative to them.
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, _ := strconv.Atoi(exponent)
+	n := exp + 1
+	k := len(digits)
+
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+
+	exponent = "+" + strconv.Itoa(n-1)
+	if n-1 < 0 {
+		exponent = "-" + strconv.Itoa(1-n)
+	}
+
+	if k == 1 {
+		return sign + digits + "e" + exponent
+	}
+
+	return sign + digits[:1] + "." + digits[1:] + "e" + exponent
+}
+
+func apiCanonical(L *lua.LState) int {
+	value := L.CheckAny(1)
+	opts := checkEncodeOptions(L, 2)
+
+	data, err := CanonicalWithOptions(value, opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LString(string(data)))
+
+	return 1
+}
@@ -0,0 +1,96 @@
+package json_test
+
+import (
+	"math"
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestCanonicalLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.canonical) == "function")
+
+	-- Test key order, whitespace and escaping
+	local out = json.canonical({b = {2, 1}, a = "<&>", c = {z = json.null, y = true}})
+	assert(out == '{"a":"<&>","b":[2,1],"c":{"y":true,"z":null}}', out)
+
+	-- Test that insertion order does not matter
+	local first = json.object()
+	first.x = 1
+	first.y = 2
+	local second = json.ordered()
+	second.y = 2
+	second.x = 1
+	assert(json.canonical(first) == json.canonical(second))
+
+	-- Test options
+	local out = json.canonical({[1] = "a", [3] = "c"}, {sparseArrays = "null", indent = "  "})
+	assert(out == '["a",null,"c"]', out)
+
+	-- Test errors
+	local out, err = json.canonical({f = function() end})
+	assert(out == nil)
+	assert(err == "cannot encode function to JSON at .f", err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    lua.LValue
+		expected string
+	}{
+		{name: "zero", value: lua.LNumber(0), expected: `0`},
+		{name: "negative zero", value: lua.LNumber(math.Copysign(0, -1)), expected: `0`},
+		{name: "integer", value: lua.LNumber(-42), expected: `-42`},
+		{name: "fraction", value: lua.LNumber(0.1), expected: `0.1`},
+		{name: "large integer", value: lua.LNumber(1e20), expected: `100000000000000000000`},
+		{name: "exponent", value: lua.LNumber(1e21), expected: `1e+21`},
+		{name: "small fraction", value: lua.LNumber(0.000001), expected: `0.000001`},
+		{name: "small exponent", value: lua.LNumber(1e-7), expected: `1e-7`},
+		{name: "max", value: lua.LNumber(math.MaxFloat64), expected: `1.7976931348623157e+308`},
+		{name: "min", value: lua.LNumber(5e-324), expected: `5e-324`},
+		{name: "digits", value: lua.LNumber(333333333.3333333), expected: `333333333.3333333`},
+		{name: "rounding", value: lua.LNumber(9007199254740993), expected: `9007199254740992`},
+		{name: "control characters", value: lua.LString("\u0001\t\n\"\\/"), expected: `"\u0001\t\n\"\\/"`},
+		{name: "non-ASCII", value: lua.LString("€ "), expected: "\"€ \""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := luajson.Canonical(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+
+	// Keys are sorted by UTF-16 code units, so U+1F600 (a surrogate pair)
+	// comes before U+FB01.
+	L := lua.NewState()
+	defer L.Close()
+
+	tbl := L.NewTable()
+	tbl.RawSetString("ﬁ", lua.LNumber(1))
+	tbl.RawSetString("\U0001f600", lua.LNumber(2))
+	tbl.RawSetString("é", lua.LNumber(3))
+
+	data, err := luajson.Canonical(tbl)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"é\":3,\"\U0001f600\":2,\"ﬁ\":1}", string(data))
+
+	_, err = luajson.Canonical(lua.LNumber(math.Inf(1)))
+	require.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package json
+
+import (
+	lua "github.com/yuin/gopher-lua"
+)
+
+// jsonTypeField is the metatable field marking a table as a JSON object or
+// array.
+const jsonTypeField = "__jsontype"
+
+// jsonOrderedField is the metatable field marking an object whose keys are
+// encoded in insertion order.
+const jsonOrderedField = "__jsonordered"
+
+const (
+	jsonTypeObject = "object"
+	jsonTypeArray  = "array"
+)
+
+// MarkObject marks tbl so that it is encoded as a JSON object, even when it is
+// empty, and returns it. The marker is a metatable, which replaces any
+// metatable tbl already has.
+func MarkObject(L *lua.LState, tbl *lua.LTable) *lua.LTable {
+	tbl.Metatable = markerMetatable(L, jsonTypeObject)
+
+	return tbl
+}
+
+// MarkArray marks tbl so that it is encoded as a JSON array and returns it.
+// The marker is a metatable, which replaces any metatable tbl already has.
+func MarkArray(L *lua.LState, tbl *lua.LTable) *lua.LTable {
+	tbl.Metatable = markerMetatable(L, jsonTypeArray)
+
+	return tbl
+}
+
+// MarkOrdered marks tbl as a JSON object whose keys are encoded in the order
+// they were first inserted, regardless of EncodeOptions.SortKeys, and returns
+// it. The marker is a metatable, which replaces any metatable tbl already has.
+func MarkOrdered(L *lua.LState, tbl *lua.LTable) *lua.LTable {
+	mt := L.NewTypeMetatable("json.ordered")
+	mt.RawSetString(jsonTypeField, lua.LString(jsonTypeObject))
+	mt.RawSetString(jsonOrderedField, lua.LTrue)
+
+	tbl.Metatable = mt
+
+	return tbl
+}
+
+func markerMetatable(L *lua.LState, jsonType string) *lua.LTable {
+	mt := L.NewTypeMetatable("json." + jsonType)
+	mt.RawSetString(jsonTypeField, lua.LString(jsonType))
+
+	return mt
+}
+
+// tableType returns the JSON type tbl is marked with, or the empty string if
+// it is not marked. Any metatable with a __jsontype field of "object" or
+// "array" counts as a marker.
+func tableType(tbl *lua.LTable) string {
+	mt, ok := tbl.Metatable.(*lua.LTable)
+	if !ok {
+		return ""
+	}
+
+	switch jsonType := mt.RawGetString(jsonTypeField).String(); jsonType {
+	case jsonTypeObject, jsonTypeArray:
+		return jsonType
+	default:
+		return ""
+	}
+}
+
+// isOrderedTable reports whether tbl is marked as an ordered object. Any
+// metatable with a true __jsonordered field counts as a marker.
+func isOrderedTable(tbl *lua.LTable) bool {
+	mt, ok := tbl.Metatable.(*lua.LTable)
+
+	return ok && lua.LVAsBool(mt.RawGetString(jsonOrderedField))
+}
+
+// isObjectTable reports whether tbl holds a JSON object, either because it is
+// marked as one or because its keys are strings.
+func isObjectTable(tbl *lua.LTable) bool {
+	if isOrderedTable(tbl) {
+		return true
+	}
+
+	switch tableType(tbl) {
+	case jsonTypeObject:
+		return true
+	case jsonTypeArray:
+		return false
+	}
+
+	first, _ := tbl.Next(lua.LNil)
+
+	return first.Type() == lua.LTString
+}
+
+func apiObject(L *lua.LState) int {
+	tbl := L.OptTable(1, L.NewTable())
+
+	L.Push(MarkObject(L, tbl))
+
+	return 1
+}
+
+func apiArray(L *lua.LState) int {
+	tbl := L.OptTable(1, L.NewTable())
+
+	L.Push(MarkArray(L, tbl))
+
+	return 1
+}
+
+func apiOrdered(L *lua.LState) int {
+	tbl := L.OptTable(1, L.NewTable())
+
+	L.Push(MarkOrdered(L, tbl))
+
+	return 1
+}
@@ -0,0 +1,88 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestContainersLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.object) == "function")
+	assert(type(json.array) == "function")
+
+	-- Test constructors
+	assert(json.encode(json.object()) == "{}")
+	assert(json.encode(json.array()) == "[]")
+	assert(json.encode({metadata = {annotations = json.object()}}) == '{"metadata":{"annotations":{}}}')
+
+	-- Test marking existing tables
+	local tbl = {name = "x"}
+	assert(json.object(tbl) == tbl)
+	tbl.name = nil
+	assert(json.encode(tbl) == "{}")
+
+	local _, err = json.encode(json.object({1, 2}))
+	assert(string.find(err, "mixed or invalid key types"), err)
+
+	local _, err = json.encode(json.array({name = "x"}))
+	assert(string.find(err, "mixed or invalid key types"), err)
+
+	-- Test user metatables carrying the marker
+	assert(json.encode(setmetatable({}, {__jsontype = "object"})) == "{}")
+
+	-- Test clearing a decoded object
+	local obj = json.decode('{"metadata":{"annotations":{"a":"b"}},"items":[]}', {markContainers = true})
+	obj.metadata.annotations.a = nil
+	assert(json.encode(obj) == '{"items":[],"metadata":{"annotations":{}}}')
+
+	local obj = json.fromYAML("data: {}\nlist: []\n", {markContainers = true})
+	assert(json.encode(obj) == '{"data":{},"list":[]}')
+	assert(string.find(json.toYAML(obj), "data: {}", 1, true))
+
+	-- Test decoded markers steer JSON Pointer keys
+	local obj = json.decode('{"labels":{}}', {markContainers = true})
+	assert(json.pointer.set(obj, "/labels/0", "zero"))
+	assert(obj.labels["0"] == "zero")
+
+	-- Test patches keep empty objects
+	local patched = json.patch(json.object(), {{op = "add", path = "/spec", value = json.object()}})
+	assert(json.encode(patched) == '{"spec":{}}')`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMarkContainers(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	data, err := luajson.Encode(luajson.MarkObject(L, L.NewTable()))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	data, err = luajson.Encode(luajson.MarkArray(L, L.NewTable()))
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(data))
+
+	value, err := luajson.DecodeWithOptions(L, []byte(`{"a":{},"b":[]}`), luajson.DecodeOptions{MarkContainers: true})
+	require.NoError(t, err)
+
+	data, err = luajson.Encode(value)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":{},"b":[]}`, string(data))
+
+	yamlData, err := luajson.ToYAML(value)
+	require.NoError(t, err)
+	assert.Contains(t, string(yamlData), "a: {}")
+}
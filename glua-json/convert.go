@@ -0,0 +1,624 @@
+package json
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var (
+	marshalerType     = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	timeType          = reflect.TypeFor[time.Time]()
+)
+
+// maxToLuaDepth bounds the nesting of the values ToLuaWithOptions converts,
+// so that cyclic values fail instead of overflowing the stack.
+const maxToLuaDepth = 1000
+
+var errToLuaDepth = errors.New("cannot convert value nested too deeply to Lua, it may be cyclic")
+
+// ToLua converts value to a Lua value directly, producing the same result as
+// encoding value with encoding/json and decoding it with Decode.
+func ToLua(L *lua.LState, value any) (lua.LValue, error) {
+	return ToLuaWithOptions(L, value, DecodeOptions{})
+}
+
+// ToLuaWithOptions converts value to a Lua value directly, producing the same
+// result as encoding value with encoding/json and decoding it with
+// DecodeWithOptions. The PreserveNull, MarkContainers and UseNumber options
+// are honoured.
+//
+// Maps, slices, arrays, pointers, numbers of any width, strings, booleans,
+// time.Time, []byte and structs, through their json tags, are converted, as
+// are types implementing json.Marshaler or encoding.TextMarshaler. Lua values
+// are returned as they are. Values nested more than 1000 levels deep, such as
+// cyclic ones, are rejected with an error.
+func ToLuaWithOptions(L *lua.LState, value any, opts DecodeOptions) (lua.LValue, error) {
+	return toLua(L, value, opts, 0)
+}
+
+// toLua converts value, nested depth levels deep, like ToLuaWithOptions.
+func toLua(L *lua.LState, value any, opts DecodeOptions, depth int) (lua.LValue, error) {
+	if depth > maxToLuaDepth {
+		return nil, errToLuaDepth
+	}
+
+	switch converted := value.(type) {
+	case nil:
+		return luaNull(L, opts), nil
+	case lua.LValue:
+		return converted, nil
+	case bool:
+		return lua.LBool(converted), nil
+	case string:
+		return lua.LString(converted), nil
+	case float64:
+		return lua.LNumber(converted), nil
+	case int64:
+		return luaInteger(L, converted, opts), nil
+	case int:
+		return luaInteger(L, int64(converted), opts), nil
+	case json.Number:
+		return luaNumber(L, converted, opts), nil
+	case time.Time:
+		return lua.LString(converted.Format(time.RFC3339Nano)), nil
+	case map[string]any:
+		if converted == nil {
+			return luaNull(L, opts), nil
+		}
+
+		tbl := L.CreateTable(0, len(converted))
+
+		for key, item := range converted {
+			v, err := toLua(L, item, opts, depth+1)
+			if err != nil {
+				return nil, err
+			}
+
+			tbl.RawSetString(key, v)
+		}
+
+		return luaObject(L, tbl, opts), nil
+	case []any:
+		if converted == nil {
+			return luaNull(L, opts), nil
+		}
+
+		arr := L.CreateTable(len(converted), 0)
+
+		for _, item := range converted {
+			v, err := toLua(L, item, opts, depth+1)
+			if err != nil {
+				return nil, err
+			}
+
+			arr.Append(v)
+		}
+
+		return luaArray(L, arr, opts), nil
+	}
+
+	return reflectToLua(L, reflect.ValueOf(value), opts, depth)
+}
+
+func reflectToLua(L *lua.LState, v reflect.Value, opts DecodeOptions, depth int) (lua.LValue, error) {
+	if depth > maxToLuaDepth {
+		return nil, errToLuaDepth
+	}
+
+	if !v.IsValid() {
+		return luaNull(L, opts), nil
+	}
+
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return luaNull(L, opts), nil
+		}
+
+		if v.Type() != marshalerType && !v.Type().Implements(marshalerType) &&
+			!v.Type().Implements(textMarshalerType) {
+			return reflectToLua(L, v.Elem(), opts, depth+1)
+		}
+	}
+
+	if v.Type() == timeType {
+		return lua.LString(v.Interface().(time.Time).Format(time.RFC3339Nano)), nil
+	}
+
+	if marshaler, ok := asInterface[json.Marshaler](v, marshalerType); ok {
+		data, err := marshaler.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		return DecodeWithOptions(L, data, opts)
+	}
+
+	if marshaler, ok := asInterface[encoding.TextMarshaler](v, textMarshalerType); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+
+		return lua.LString(text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return lua.LBool(v.Bool()), nil
+	case reflect.String:
+		return lua.LString(v.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return luaInteger(L, v.Int(), opts), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := v.Uint(); n > math.MaxInt64 {
+			return luaNumber(L, json.Number(strconv.FormatUint(n, 10)), opts), nil
+		}
+
+		return luaInteger(L, int64(v.Uint()), opts), nil
+	case reflect.Float32:
+		// Formatted with float32 precision first, as encoding/json does, so
+		// that float32(0.1) is 0.1.
+		f, err := strconv.ParseFloat(strconv.FormatFloat(v.Float(), 'g', -1, 32), 64)
+		if err != nil {
+			return nil, err
+		}
+
+		return lua.LNumber(f), nil
+	case reflect.Float64:
+		return lua.LNumber(v.Float()), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return luaNull(L, opts), nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return lua.LString(base64.StdEncoding.EncodeToString(v.Bytes())), nil
+		}
+
+		return reflectArrayToLua(L, v, opts, depth)
+	case reflect.Array:
+		return reflectArrayToLua(L, v, opts, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			return luaNull(L, opts), nil
+		}
+
+		return reflectMapToLua(L, v, opts, depth)
+	case reflect.Struct:
+		return reflectStructToLua(L, v, opts, depth)
+	default:
+		return nil, fmt.Errorf("cannot convert %s to Lua", v.Type())
+	}
+}
+
+// asInterface returns v, or a pointer to v if only the pointer implements
+// typ, as an I.
+func asInterface[I any](v reflect.Value, typ reflect.Type) (I, bool) {
+	var zero I
+
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return zero, false
+	}
+
+	if v.Type().Implements(typ) {
+		i, ok := v.Interface().(I)
+
+		return i, ok
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(typ) {
+		i, ok := v.Addr().Interface().(I)
+
+		return i, ok
+	}
+
+	return zero, false
+}
+
+func reflectArrayToLua(L *lua.LState, v reflect.Value, opts DecodeOptions, depth int) (lua.LValue, error) {
+	arr := L.CreateTable(v.Len(), 0)
+
+	for i := range v.Len() {
+		item, err := reflectToLua(L, v.Index(i), opts, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		arr.Append(item)
+	}
+
+	return luaArray(L, arr, opts), nil
+}
+
+func reflectMapToLua(L *lua.LState, v reflect.Value, opts DecodeOptions, depth int) (lua.LValue, error) {
+	tbl := L.CreateTable(0, v.Len())
+
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKeyName(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := reflectToLua(L, iter.Value(), opts, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		tbl.RawSetString(key, item)
+	}
+
+	return luaObject(L, tbl, opts), nil
+}
+
+// mapKeyName returns the object key encoding/json writes for a map key.
+func mapKeyName(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+
+	if marshaler, ok := asInterface[encoding.TextMarshaler](key, textMarshalerType); ok {
+		text, err := marshaler.MarshalText()
+
+		return string(text), err
+	}
+
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("cannot convert map key of type %s to Lua", key.Type())
+	}
+}
+
+func reflectStructToLua(L *lua.LState, v reflect.Value, opts DecodeOptions, depth int) (lua.LValue, error) {
+	fields := structFields(v.Type())
+	tbl := L.CreateTable(0, len(fields))
+
+	for _, field := range fields {
+		fv, ok := fieldByIndex(v, field.index)
+		if !ok || (field.omitEmpty && isEmptyValue(fv)) || (field.omitZero && fv.IsZero()) {
+			continue
+		}
+
+		item, err := reflectToLua(L, fv, opts, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		tbl.RawSetString(field.name, item)
+	}
+
+	return luaObject(L, tbl, opts), nil
+}
+
+// fieldByIndex returns the field of v at index, or false if it is reached
+// through a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	default:
+		return false
+	}
+}
+
+// structField is a field of a struct as encoding/json sees it.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	omitZero  bool
+}
+
+var structFieldsCache sync.Map // map[reflect.Type][]structField
+
+// structFields returns the fields encoding/json encodes for typ, with the
+// fields of embedded structs without a json name inlined. A field hides the
+// fields of the same name of the structs it is embedded with.
+func structFields(typ reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(typ); ok {
+		return fields.([]structField)
+	}
+
+	var fields []structField
+
+	seen := make(map[string]bool)
+
+	// Fields are visited breadth first, so that the shallower of two fields
+	// of the same name wins.
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	current := []embedded{{typ: typ}}
+	for len(current) > 0 {
+		var next []embedded
+
+		for _, e := range current {
+			for i := range e.typ.NumField() {
+				sf := e.typ.Field(i)
+
+				index := append(append([]int(nil), e.index...), i)
+
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+
+				name, options, _ := strings.Cut(tag, ",")
+
+				if sf.Anonymous && name == "" {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+
+					if ft.Kind() == reflect.Struct {
+						next = append(next, embedded{typ: ft, index: index})
+
+						continue
+					}
+				}
+
+				if !sf.IsExported() {
+					continue
+				}
+
+				if name == "" {
+					name = sf.Name
+				}
+
+				if seen[name] {
+					continue
+				}
+
+				seen[name] = true
+
+				fields = append(fields, structField{
+					name:      name,
+					index:     index,
+					omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+					omitZero:  strings.Contains(","+options+",", ",omitzero,"),
+				})
+			}
+		}
+
+		current = next
+	}
+
+	structFieldsCache.Store(typ, fields)
+
+	return fields
+}
+
+func luaNull(L *lua.LState, opts DecodeOptions) lua.LValue {
+	if opts.PreserveNull {
+		return Null(L)
+	}
+
+	return lua.LNil
+}
+
+func luaInteger(L *lua.LState, n int64, opts DecodeOptions) lua.LValue {
+	if opts.UseNumber && (n > maxExactInteger || n < -maxExactInteger) {
+		return decodeNumber(L, json.Number(strconv.FormatInt(n, 10)))
+	}
+
+	return lua.LNumber(n)
+}
+
+func luaNumber(L *lua.LState, number json.Number, opts DecodeOptions) lua.LValue {
+	if opts.UseNumber {
+		return decodeNumber(L, number)
+	}
+
+	f, err := number.Float64()
+	if err != nil {
+		return lua.LString(number)
+	}
+
+	return lua.LNumber(f)
+}
+
+func luaObject(L *lua.LState, tbl *lua.LTable, opts DecodeOptions) lua.LValue {
+	if opts.MarkContainers {
+		MarkObject(L, tbl)
+	}
+
+	return tbl
+}
+
+func luaArray(L *lua.LState, arr *lua.LTable, opts DecodeOptions) lua.LValue {
+	if opts.MarkContainers {
+		MarkArray(L, arr)
+	}
+
+	return arr
+}
+
+// FromLua converts value to the Go representation encoding/json decodes
+// JSON to, as if value were encoded with Encode and decoded into an any, but
+// without the round-trip. Integral numbers become int64, other numbers
+// float64, and large integers decoded with UseNumber json.Number.
+func FromLua(value lua.LValue) (any, error) {
+	state := &encodeState{
+		opts:    EncodeOptions{SortKeys: true},
+		visited: make(map[*lua.LTable]bool),
+	}
+
+	result, err := jsonValue{value, state}.toGo()
+	if err != nil {
+		return nil, withPathSegment(err, "")
+	}
+
+	return result, nil
+}
+
+func (j jsonValue) toGo() (any, error) {
+	switch converted := j.LValue.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(converted), nil
+	case lua.LString:
+		return string(converted), nil
+	case lua.LNumber:
+		return goNumber(float64(converted))
+	case *lua.LUserData:
+		if IsNull(converted) {
+			return nil, nil
+		}
+
+		if number, ok := largeNumber(converted); ok {
+			return number, nil
+		}
+
+		data, ok, err := j.marshalCustom()
+		if !ok {
+			return nil, invalidTypeError(j.LValue.Type())
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := unmarshal(data, true)
+		if err != nil {
+			return nil, err
+		}
+
+		return normalizeNumbers(value), nil
+	case *lua.LTable:
+		return j.tableToGo(converted)
+	default:
+		return nil, invalidTypeError(j.LValue.Type())
+	}
+}
+
+func (j jsonValue) tableToGo(tbl *lua.LTable) (any, error) {
+	shape, err := j.state.shape(tbl)
+	if err != nil {
+		return nil, err
+	}
+
+	if shape.array {
+		if shape.length == 0 && j.state.emptyObjects && tableType(tbl) == "" {
+			return map[string]any{}, nil
+		}
+
+		emptyObjects := j.state.emptyObjects
+		j.state.emptyObjects = false
+
+		defer func() { j.state.emptyObjects = emptyObjects }()
+
+		arr := make([]any, shape.length)
+
+		for i := range arr {
+			arr[i], err = jsonValue{tbl.RawGetInt(i + 1), j.state}.toGo()
+			if err != nil {
+				return nil, withPathSegment(err, fmt.Sprintf("[%d]", i+1))
+			}
+		}
+
+		return arr, nil
+	}
+
+	names, values, err := objectEntries(tbl, shape.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := make(map[string]any, len(names))
+
+	for _, name := range names {
+		obj[name], err = jsonValue{values[name], j.state}.toGo()
+		if err != nil {
+			return nil, withPathSegment(err, keySegment(name))
+		}
+	}
+
+	return obj, nil
+}
+
+// goNumber returns f as an int64 if it is integral and in range, and as a
+// float64 otherwise.
+func goNumber(f float64) (any, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		_, err := json.Marshal(f)
+
+		return nil, err
+	}
+
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return int64(f), nil
+	}
+
+	return f, nil
+}
+
+// normalizeNumbers replaces the json.Number values of value with the numbers
+// FromLua returns.
+func normalizeNumbers(value any) any {
+	switch converted := value.(type) {
+	case json.Number:
+		if n, err := converted.Int64(); err == nil {
+			return n
+		}
+
+		if !strings.ContainsAny(converted.String(), ".eE") {
+			return converted
+		}
+
+		f, _ := converted.Float64()
+
+		return f
+	case []any:
+		for i, item := range converted {
+			converted[i] = normalizeNumbers(item)
+		}
+	case map[string]any:
+		for key, item := range converted {
+			converted[key] = normalizeNumbers(item)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,221 @@
+package json_test
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+type containerPort struct {
+	Name          string `json:"name,omitempty"`
+	ContainerPort int32  `json:"containerPort"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+type objectMeta struct {
+	Name              string            `json:"name"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+}
+
+type container struct {
+	Name    string          `json:"name"`
+	Ports   []containerPort `json:"ports,omitempty"`
+	Args    []string        `json:"args"`
+	Ignored string          `json:"-"`
+	private string
+}
+
+type pod struct {
+	objectMeta `json:",inline"`
+
+	Kind       string                `json:"kind"`
+	Containers []container           `json:"containers"`
+	Replicas   *int                  `json:"replicas,omitempty"`
+	Weights    map[int]float32       `json:"weights"`
+	Data       []byte                `json:"data"`
+	Address    net.IP                `json:"address"`
+	Raw        json.RawMessage       `json:"raw"`
+	Extra      map[string]any        `json:"extra"`
+	Status     *struct{ Ready bool } `json:"status"`
+}
+
+func TestToLua(t *testing.T) {
+	replicas := 3
+
+	value := pod{
+		objectMeta: objectMeta{
+			Name:              "web",
+			Labels:            map[string]string{"app": "web"},
+			CreationTimestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		Kind: "Pod",
+		Containers: []container{
+			{Name: "nginx", Ports: []containerPort{{ContainerPort: 80}}, Ignored: "x", private: "y"},
+		},
+		Replicas: &replicas,
+		Weights:  map[int]float32{1: 0.5},
+		Data:     []byte("hello"),
+		Address:  net.ParseIP("10.0.0.1"),
+		Raw:      json.RawMessage(`{"b":[1,2]}`),
+		Extra: map[string]any{
+			"int8":   int8(-8),
+			"uint64": uint64(math.MaxUint64),
+			"float":  1.5,
+			"nested": []any{map[string]any{}, nil},
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, opts := range []luajson.DecodeOptions{
+		{},
+		{PreserveNull: true, MarkContainers: true, UseNumber: true},
+	} {
+		lv, err := luajson.ToLuaWithOptions(L, value, opts)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+
+		expected, err := luajson.DecodeWithOptions(L, data, opts)
+		require.NoError(t, err)
+
+		actualJSON, err := luajson.Encode(lv)
+		require.NoError(t, err)
+
+		expectedJSON, err := luajson.Encode(expected)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+	}
+
+	lv, err := luajson.ToLua(L, value)
+	require.NoError(t, err)
+
+	tbl := lv.(*lua.LTable)
+	assert.Equal(t, lua.LString("web"), tbl.RawGetString("name"))
+	assert.Equal(t, lua.LString("2024-01-02T03:04:05Z"), tbl.RawGetString("creationTimestamp"))
+	assert.Equal(t, lua.LNumber(3), tbl.RawGetString("replicas"))
+	assert.Equal(t, lua.LString("10.0.0.1"), tbl.RawGetString("address"))
+	assert.Equal(t, lua.LNil, tbl.RawGetString("status"))
+
+	one, three := 1, 3
+
+	for _, list := range []any{[]any{1, nil, 3}, []*int{&one, nil, &three}} {
+		lv, err = luajson.ToLua(L, list)
+		require.NoError(t, err)
+
+		data, err := luajson.Encode(lv)
+		require.NoError(t, err)
+		assert.Equal(t, "[1,3]", string(data))
+
+		lv, err = luajson.ToLuaWithOptions(L, list, luajson.DecodeOptions{PreserveNull: true})
+		require.NoError(t, err)
+
+		data, err = luajson.Encode(lv)
+		require.NoError(t, err)
+		assert.Equal(t, "[1,null,3]", string(data))
+	}
+
+	_, err = luajson.ToLua(L, map[string]any{"ch": make(chan int)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot convert chan int to Lua")
+
+	lv, err = luajson.ToLua(L, float32(0.1))
+	require.NoError(t, err)
+	assert.Equal(t, lua.LNumber(0.1), lv)
+
+	type node struct {
+		Next *node `json:"next"`
+	}
+
+	cyclic := &node{}
+	cyclic.Next = cyclic
+
+	cyclicMap := map[string]any{}
+	cyclicMap["self"] = cyclicMap
+
+	cyclicList := []any{nil}
+	cyclicList[0] = cyclicList
+
+	for _, value := range []any{cyclic, cyclicMap, cyclicList} {
+		_, err = luajson.ToLua(L, value)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "nested too deeply")
+	}
+}
+
+func TestFromLua(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	luajson.Preload(L)
+
+	require.NoError(t, L.DoString(`
+	local json = require("json")
+	value = {
+		kind = "Pod",
+		spec = {
+			replicas = 3,
+			ratio = 0.25,
+			containers = {{name = "web", args = json.array()}},
+			selector = json.object(),
+			empty = json.null,
+		},
+		uid = json.decode("12345678901234567891", {useNumber = true}),
+	}
+	sparse = {spec = {1, nil, 3}}`))
+
+	value, err := luajson.FromLua(L.GetGlobal("value"))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"kind": "Pod",
+		"spec": map[string]any{
+			"replicas":   int64(3),
+			"ratio":      0.25,
+			"containers": []any{map[string]any{"name": "web", "args": []any{}}},
+			"selector":   map[string]any{},
+			"empty":      nil,
+		},
+		"uid": json.Number("12345678901234567891"),
+	}, value)
+
+	_, err = luajson.FromLua(L.GetGlobal("sparse"))
+	require.Error(t, err)
+	assert.Equal(t, "cannot encode sparse array at .spec", err.Error())
+
+	_, err = luajson.FromLua(lua.LNumber(math.Inf(1)))
+	require.Error(t, err)
+
+	_, err = luajson.FromLua(L.NewFunction(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot encode function to JSON")
+}
+
+func TestFromLuaRegisteredEncoder(t *testing.T) {
+	type version struct{ major, minor int }
+
+	luajson.RegisterEncoder(func(v version) ([]byte, error) {
+		return json.Marshal(map[string]int{"major": v.major, "minor": v.minor})
+	})
+
+	L := lua.NewState()
+	defer L.Close()
+
+	ud := L.NewUserData()
+	ud.Value = version{major: 1, minor: 2}
+
+	value, err := luajson.FromLua(ud)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"major": int64(1), "minor": int64(2)}, value)
+}
@@ -4,28 +4,231 @@
 //
 // The following functions are exposed by the library:
 //
-//	decode(string):   Decodes a JSON string. Returns nil and an error string if
-//	                  the string could not be decoded.
-//	encode(value):    Encodes a value into a JSON string. Returns nil and an error
-//	                  string if the value could not be encoded.
-//	fromYAML(string): Decodes a YAML string. Returns nil and an error string if
-//	                  the string could not be decoded.
+//	decode(string[, options]):
+//	                  Decodes a JSON string. Returns nil and an error string if
+//	                  the string could not be decoded. See below for options.
+//	encode(value[, options]):
+//	                  Encodes a value into a JSON string. Returns nil and an error
+//	                  string if the value could not be encoded, naming where the
+//	                  offending value is, as in
+//	                  "cannot encode sparse array at .spec.containers[3].ports".
+//	                  See below for options.
+//	decodeLines(string[, options]):
+//	                  Decodes each line of a JSON Lines (NDJSON) string and
+//	                  returns them as a list, skipping blank lines. null lines
+//	                  are returned as json.null. Returns nil and an error string
+//	                  naming the failing line, such as "line 3: ...". Takes the
+//	                  same options as decode; maxBytes limits the whole string.
+//	lines(string[, options]):
+//	                  Returns an iterator over the lines of a JSON Lines string
+//	                  for use in for loops, yielding each decoded value and its
+//	                  line number. Raises an error naming the failing line.
+//	                  Takes the same options as decodeLines.
+//	encodeLines(list[, options]):
+//	                  Encodes each value of list on its own line, each followed
+//	                  by a newline. Returns nil and an error string naming the
+//	                  failing value. Takes the same options as encode, except
+//	                  prefix and indent.
+//	canonical(value[, options]):
+//	                  Encodes a value into its RFC 8785 canonical JSON string:
+//	                  no whitespace, keys sorted by UTF-16 code units, numbers
+//	                  formatted as in JavaScript and minimal string escaping.
+//	                  The same value always gives the same string, so that it
+//	                  can be hashed, as in sprig.sha256sum(json.canonical(v)).
+//	                  Returns nil and an error string like encode. Takes the
+//	                  sparseArrays and mixedKeys options of encode.
+//	fromYAML(string[, options]):
+//	                  Decodes a YAML string. Returns nil and an error string if
+//	                  the string could not be decoded. Takes the same options as
+//	                  decode.
 //	toYAML(value):    Encodes a value into a YAML string. Returns nil and an error
 //	                  string if the value could not be encoded.
+//	fromYAMLAll(string[, options]):
+//	                  Decodes every "---" separated document of a YAML string and
+//	                  returns them as a list, skipping empty documents. Returns
+//	                  nil and an error string naming the failing document, such
+//	                  as "document 2: ...". Takes the same options as decode.
+//	toYAMLAll(list):  Encodes each value of list into a YAML document and joins
+//	                  them with "---" separators. Returns nil and an error string
+//	                  naming the failing document.
+//	patch(doc, ops):  Applies a list of RFC 6902 JSON Patch operations (add,
+//	                  remove, replace, move, copy, test) to doc and returns the
+//	                  patched copy. Returns nil and an error string if an
+//	                  operation fails. Use json.null for null values. test
+//	                  compares values as equal does, except that empty tables,
+//	                  arrays and objects all match each other.
+//	diff(a, b):       Returns the list of RFC 6902 JSON Patch operations that
+//	                  transforms a into b.
+//	equal(a, b):      Returns whether a and b encode to equivalent JSON,
+//	                  regardless of key order and of how numbers are formatted.
+//	                  An empty table and json.object() differ, as they encode
+//	                  to [] and {}. Returns nil and an error string if either
+//	                  value could not be encoded.
+//	delta(a, b):      Returns a table with added, removed and changed lists of
+//	                  the JSON Pointers at which a and b differ, compared as by
+//	                  equal. A value whose JSON type changes is listed as
+//	                  changed. Returns nil and an error string if either value
+//	                  could not be encoded.
+//	mergePatch(target, patch):
+//	                  Applies an RFC 7386 JSON Merge Patch to target and returns
+//	                  the merged copy. json.null values in patch delete keys;
+//	                  decode patches with preserveNull, as plain nil values are
+//	                  dropped and delete nothing. Empty tables in patch are
+//	                  empty objects, which change nothing; use json.array() to
+//	                  set an empty array.
+//	strategicMergePatch(target, patch[, mergeKeys]):
+//	                  Like mergePatch, but lists named in mergeKeys (a table of
+//	                  list field name to item key field, defaulting to the
+//	                  well-known Kubernetes fields such as containers and env)
+//	                  are merged item by item. Objects may carry a "$patch"
+//	                  directive of "replace" or "delete".
+//	pointer.get(value, pointer):
+//	                  Returns the value an RFC 6901 JSON Pointer such as
+//	                  "/spec/containers/0/image" refers to. Array indices are
+//	                  zero-based and translated to Lua's one-based tables.
+//	                  Returns nil and an error string such as
+//	                  "path not found at /spec/template" if the path is missing.
+//	pointer.set(table, pointer, value):
+//	                  Sets the location a JSON Pointer refers to, creating
+//	                  missing intermediate tables. "-" appends to an array.
+//	                  Returns true, or nil and an error string.
+//	pointer.remove(table, pointer):
+//	                  Removes the location a JSON Pointer refers to and returns
+//	                  the removed value, or nil and an error string.
+//	yaml.parseDocument(string):
+//	                  Parses a YAML document into a handle that keeps its
+//	                  comments, anchors, aliases and scalar styles. Returns nil
+//	                  and an error string if the string could not be parsed.
+//	                  The handle has the following methods, taking JSON Pointers
+//	                  like the pointer functions:
+//	                    doc:get(pointer): returns the value, or nil and an error
+//	                      string.
+//	                    doc:set(pointer, value): sets the value, creating missing
+//	                      mappings, and returns true, or nil and an error string.
+//	                      Replaced nodes keep their comments and anchor.
+//	                    doc:remove(pointer): removes and returns the value, or
+//	                      nil and an error string. Anchored nodes still
+//	                      aliased elsewhere move to their first alias.
+//	                    doc:render(): returns the document as a YAML string,
+//	                      with the indentation style of the parsed document.
+//	                      Blank lines are not kept.
+//	schema.compile(schema[, options]):
+//	                  Compiles a JSON Schema, given as a table or as a JSON or
+//	                  YAML string, into a handle. Schemas follow draft 2020-12
+//	                  unless they declare another draft with $schema, and may
+//	                  not refer to other documents. Use json.object() for empty
+//	                  objects in schema tables. With options.openAPI set, the
+//	                  schema is read as an OpenAPI v3 schema, such as the
+//	                  openAPIV3Schema of a CRD, honouring nullable, boolean
+//	                  exclusiveMinimum and exclusiveMaximum, and
+//	                  x-kubernetes-int-or-string. Returns nil and an error
+//	                  string if the schema is invalid. The handle has the
+//	                  following method:
+//	                    schema:validate(value): returns the list of violations,
+//	                      empty if value is valid, each a table with the JSON
+//	                      Pointer of the offending value as path ("" for value
+//	                      itself) and a message. Empty tables are valid as
+//	                      objects too, unless marked with json.array(). Returns
+//	                      nil and an error string if value cannot be encoded.
+//	object([table]):  Marks table, or a new table, so that it is encoded as a
+//	                  JSON object even when empty, and returns it. The marker is
+//	                  a metatable with a __jsontype field of "object"; any
+//	                  metatable with that field is honoured.
+//	array([table]):   Marks table, or a new table, so that it is encoded as a
+//	                  JSON array, and returns it. The marker is a metatable with
+//	                  a __jsontype field of "array".
+//	pairs(value):     Returns an iterator over value like pairs, but honouring
+//	                  a __pairs metamethod, which pairs ignores. Use it to
+//	                  iterate over proxies (see below) as well as tables.
+//	ordered([table]): Marks table, or a new table, as an object whose keys are
+//	                  encoded in the order they were first inserted, regardless
+//	                  of the sortKeys option, and returns it. The marker is a
+//	                  metatable with a true __jsonordered field.
+//	jsonpath(value, expr):
+//	                  Evaluates a kubectl-style JSONPath expression such as
+//	                  '{.status.conditions[?(@.type=="Ready")].status}' and
+//	                  returns the list of matches. Returns nil and an error
+//	                  string if the expression is invalid.
+//
+// The following fields are exposed by the library:
+//
+//	null:             Sentinel standing for an explicit JSON null. Unlike nil, it
+//	                  can be stored in tables, and it is encoded to null.
+//
+// The following decode options are supported:
+//
+//	preserveNull:     When true, null is decoded to json.null instead of nil, so
+//	                  that object keys holding null are kept.
+//	markContainers:   When true, decoded objects and arrays are marked as with
+//	                  json.object and json.array, so that they keep their type
+//	                  when encoded, even once emptied.
+//	useNumber:        When true, numbers are decoded without going through
+//	                  float64: integers beyond 2^53 in magnitude become userdata
+//	                  that keep their exact digits, convert to strings with
+//	                  tostring, compare with each other, and encode unchanged.
+//	preserveOrder:    When true, object keys are inserted in the order they
+//	                  appear in the input and objects are marked as with
+//	                  json.ordered, so that encode and toYAML reproduce that
+//	                  order.
+//	objectHook:       Function called with each decoded object, after the values
+//	                  it holds, whose return value replaces the object.
+//	numberHook:       Function called with the literal of each decoded number as
+//	                  a string, whose return value replaces the number.
+//	maxBytes:         Largest input size in bytes.
+//	maxDepth:         Deepest nesting of arrays and objects.
+//	maxElements:      Largest total number of array elements and object members.
+//
+// The max options can only lower the limits the module was preloaded with
+// through PreloadWithLimits. When an input exceeds a limit, decoding returns nil
+// and an error string such as "limit exceeded: nesting depth exceeds 256".
+//
+// Errors raised by hooks propagate to the caller.
+//
+// The following encode options are supported:
+//
+//	indent:           String used to indent nested values. The output is compact
+//	                  unless indent or prefix is set.
+//	prefix:           String each line of indented output starts with.
+//	escapeHTML:       Whether <, > and & in strings are escaped as \u003c and
+//	                  friends. Defaults to true.
+//	sortKeys:         Whether object keys are sorted. Otherwise they follow the
+//	                  iteration order of the table. Defaults to true. Tables
+//	                  marked with json.ordered always keep their order.
+//	sparseArrays:     How tables whose numeric keys are not the sequence 1..n are
+//	                  encoded: "error" (the default), "null" to fill the missing
+//	                  elements up to the largest index with null, or "object" to
+//	                  encode an object with the keys converted to strings. Like
+//	                  with lua-cjson, "null" still fails arrays larger than 10
+//	                  elements where more than half would be null.
+//	mixedKeys:        How tables holding both numeric and string keys, or marked
+//	                  as objects and holding numeric keys, are encoded: "error"
+//	                  (the default), or "object" to convert the numeric keys to
+//	                  strings.
 //
 // The following types are supported:
 //
-//	Lua      | JSON/YAML
-//	---------+----------
-//	nil      | null
-//	number   | number
-//	string   | string
-//	table    | object: when table is non-empty and has only string keys
-//	         | array:  when table is empty, or has only sequential numeric keys
-//	         |         starting from 1
+//	Lua       | JSON/YAML
+//	----------+----------
+//	nil       | null
+//	json.null | null
+//	number    | number
+//	string    | string
+//	table     | object: when table is non-empty and has only string keys, or
+//	          |         is marked as an object
+//	          | array:  when table is empty and not marked as an object, or
+//	          |         has only sequential numeric keys starting from 1
 //
+// Integral numbers are encoded without exponent. Tables and userdata with a
+// __tojson metamethod are encoded as the value it returns, and userdata whose
+// Go value has an encoder registered with RegisterEncoder are encoded by it.
 // Attempting to encode any other Lua type will result in an error.
 //
+// Go programs may hand large documents to scripts as read-only proxies created
+// with NewProxy, whose elements are converted to Lua values only when they are
+// accessed. Proxies are userdata supporting indexing, # and json.pairs, and are
+// encoded like the Go values they stand for. json.jsonpath and json.pointer.get
+// look into them as into tables.
+//
 // # Example
 //
 // Below is an example usage of the library:
@@ -36,4 +239,8 @@
 //
 //	L := lua.NewState()
 //	luajson.Preload(L)
+//
+// Scripts decoding untrusted input can be limited instead with:
+//
+//	luajson.PreloadWithLimits(L, luajson.DefaultLimits)
 package json // import "github.com/projectsveltos/lua-utils/glua-json"
@@ -0,0 +1,104 @@
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// toJSONField is the metamethod returning the value to encode in place of a
+// table or userdata.
+const toJSONField = "__tojson"
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = make(map[reflect.Type]func(any) ([]byte, error))
+)
+
+// RegisterEncoder registers encode as the encoder of userdata whose Value is
+// of type T. encode returns the JSON encoding of the value, which replaces the
+// userdata in the output. Registering a type again replaces its encoder.
+func RegisterEncoder[T any](encode func(T) ([]byte, error)) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[reflect.TypeFor[T]()] = func(value any) ([]byte, error) {
+		return encode(value.(T))
+	}
+}
+
+func lookupEncoder(value any) func(any) ([]byte, error) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	return encoders[reflect.TypeOf(value)]
+}
+
+// marshalCustom returns the JSON encoding of a table or userdata with a
+// __tojson metamethod, of a proxy, or of a userdata whose Value has a
+// registered encoder. ok is false when none applies.
+func (j jsonValue) marshalCustom() (data []byte, ok bool, err error) {
+	if L := j.state.opts.State; L != nil {
+		if fn := L.GetMetaField(j.LValue, toJSONField); fn != lua.LNil {
+			replacement, err := callToJSON(L, fn, j.LValue)
+			if err != nil {
+				return nil, true, err
+			}
+
+			// A value returning itself is encoded as if it had no __tojson.
+			if replacement != j.LValue {
+				data, err = jsonValue{replacement, j.state}.MarshalJSON()
+
+				return data, true, err
+			}
+		}
+	}
+
+	ud, isUserData := j.LValue.(*lua.LUserData)
+	if !isUserData {
+		return nil, false, nil
+	}
+
+	if p, ok := ud.Value.(*proxy); ok {
+		data, err = p.marshalJSON(j.state.opts.EscapeHTML)
+
+		return data, true, err
+	}
+
+	encode := lookupEncoder(ud.Value)
+	if encode == nil {
+		return nil, false, nil
+	}
+
+	data, err = encode(ud.Value)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if !json.Valid(data) {
+		return nil, true, fmt.Errorf("encoder of %T returned invalid JSON", ud.Value)
+	}
+
+	return data, true, nil
+}
+
+func callToJSON(L *lua.LState, fn, value lua.LValue) (lua.LValue, error) {
+	err := L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, value)
+	if err != nil {
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) {
+			return nil, fmt.Errorf("%s failed: %s", toJSONField, apiErr.Object.String())
+		}
+
+		return nil, err
+	}
+
+	replacement := L.Get(-1)
+	L.Pop(1)
+
+	return replacement, nil
+}
@@ -0,0 +1,159 @@
+package json_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+type quantity struct {
+	value string
+}
+
+func TestToJSONLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+
+	-- Test tables
+	local point = setmetatable({x = 1, y = 2}, {__tojson = function(p) return {p.x, p.y} end})
+	assert(json.encode({point = point}) == '{"point":[1,2]}')
+	assert(json.toYAML({point = point}) == "point:\n- 1\n- 2\n")
+	assert(json.toYAMLAll({point}) == "- 1\n- 2\n")
+
+	-- Test returning the value itself
+	local plain = setmetatable({a = 1}, {__tojson = function(v) return v end})
+	assert(json.encode(plain) == '{"a":1}')
+
+	-- Test userdata
+	assert(json.encode({when = when, size = size}) == '{"size":"2Gi","when":"2024-01-02T03:04:05Z"}')
+
+	-- Test errors
+	local bad = setmetatable({}, {__tojson = function() error("boom") end})
+	local data, err = json.encode({items = {bad}})
+	assert(data == nil)
+	assert(string.find(err, "^__tojson failed: .*boom at .items%[1%]$"), err)
+
+	local data, err = json.encode({f = setmetatable({}, {__tojson = function() return print end})})
+	assert(data == nil)
+	assert(err == "cannot encode function to JSON at .f", err)`
+
+	luajson.RegisterEncoder(func(q quantity) ([]byte, error) {
+		return json.Marshal(q.value)
+	})
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	when := s.NewUserData()
+	when.Value = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mt := s.NewTable()
+	s.SetField(mt, "__tojson", s.NewFunction(func(L *lua.LState) int {
+		ud := L.CheckUserData(1)
+		L.Push(lua.LString(ud.Value.(time.Time).Format(time.RFC3339)))
+
+		return 1
+	}))
+	when.Metatable = mt
+	s.SetGlobal("when", when)
+
+	size := s.NewUserData()
+	size.Value = quantity{value: "2Gi"}
+	s.SetGlobal("size", size)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	type invalid struct{}
+
+	type failing struct{}
+
+	luajson.RegisterEncoder(func(d time.Duration) ([]byte, error) {
+		return json.Marshal(d.String())
+	})
+	luajson.RegisterEncoder(func(invalid) ([]byte, error) {
+		return []byte("{"), nil
+	})
+	luajson.RegisterEncoder(func(failing) ([]byte, error) {
+		return nil, errors.New("cannot encode failing")
+	})
+
+	L := lua.NewState()
+	defer L.Close()
+
+	userData := func(value any) *lua.LUserData {
+		ud := L.NewUserData()
+		ud.Value = value
+
+		return ud
+	}
+
+	tests := []struct {
+		name     string
+		input    lua.LValue
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "registered type",
+			input:    userData(90 * time.Second),
+			expected: `"1m30s"`,
+		},
+		{
+			name:    "unregistered type",
+			input:   userData(int64(1)),
+			wantErr: "cannot encode userdata to JSON",
+		},
+		{
+			name:    "invalid JSON",
+			input:   userData(invalid{}),
+			wantErr: "returned invalid JSON",
+		},
+		{
+			name:    "encoder error",
+			input:   userData(failing{}),
+			wantErr: "cannot encode failing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := luajson.Encode(tt.input)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestToJSONWithoutState(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	require.NoError(t, L.DoString(`value = setmetatable({a = 1}, {__tojson = function() return "replaced" end})`))
+
+	data, err := luajson.Encode(L.GetGlobal("value"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(data))
+
+	data, err = luajson.EncodeWithOptions(L.GetGlobal("value"), luajson.EncodeOptions{State: L})
+	require.NoError(t, err)
+	assert.Equal(t, `"replaced"`, string(data))
+}
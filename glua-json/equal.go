@@ -0,0 +1,226 @@
+package json
+
+import (
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strconv"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Changes lists the JSON Pointers of the values that differ between two
+// documents.
+type Changes struct {
+	// Added holds the locations only present in the second document.
+	Added []string
+	// Removed holds the locations only present in the first document.
+	Removed []string
+	// Changed holds the locations present in both documents with different
+	// values. Values of different JSON types, such as an object and an array,
+	// are changed rather than compared element by element.
+	Changed []string
+}
+
+// Equal reports whether a and b encode to equivalent JSON: object key order,
+// markers such as MarkOrdered and the way numbers are represented do not
+// matter, but an empty table and an empty object do, as they encode
+// differently.
+func Equal(a, b lua.LValue) (bool, error) {
+	goA, err := FromLua(a)
+	if err != nil {
+		return false, err
+	}
+
+	goB, err := FromLua(b)
+	if err != nil {
+		return false, err
+	}
+
+	return equalValues(goA, goB), nil
+}
+
+// Delta returns the locations at which a and b differ, compared as Equal
+// does. Each list is sorted.
+func Delta(a, b lua.LValue) (Changes, error) {
+	goA, err := FromLua(a)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	goB, err := FromLua(b)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	var changes Changes
+
+	deltaValues("", goA, goB, &changes)
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+
+	return changes, nil
+}
+
+func equalValues(a, b any) bool {
+	switch convertedA := a.(type) {
+	case map[string]any:
+		convertedB, ok := b.(map[string]any)
+		if !ok || len(convertedA) != len(convertedB) {
+			return false
+		}
+
+		for key, valueA := range convertedA {
+			valueB, ok := convertedB[key]
+			if !ok || !equalValues(valueA, valueB) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		convertedB, ok := b.([]any)
+		if !ok || len(convertedA) != len(convertedB) {
+			return false
+		}
+
+		for i := range convertedA {
+			if !equalValues(convertedA[i], convertedB[i]) {
+				return false
+			}
+		}
+
+		return true
+	case int64, float64, json.Number:
+		ratA, okA := numberRat(a)
+		ratB, okB := numberRat(b)
+
+		return okA && okB && ratA.Cmp(ratB) == 0
+	default:
+		return a == b
+	}
+}
+
+// numberRat returns the exact value of the number FromLua returned, or false
+// if value is not a number.
+func numberRat(value any) (*big.Rat, bool) {
+	var literal string
+
+	switch converted := value.(type) {
+	case int64:
+		literal = strconv.FormatInt(converted, 10)
+	case float64:
+		literal = strconv.FormatFloat(converted, 'g', -1, 64)
+	case json.Number:
+		literal = converted.String()
+	default:
+		return nil, false
+	}
+
+	return new(big.Rat).SetString(literal)
+}
+
+func deltaValues(path string, a, b any, changes *Changes) {
+	switch convertedA := a.(type) {
+	case map[string]any:
+		convertedB, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+
+		for key, valueA := range convertedA {
+			childPath := path + "/" + escapePointerToken(key)
+
+			valueB, ok := convertedB[key]
+			if !ok {
+				changes.Removed = append(changes.Removed, childPath)
+
+				continue
+			}
+
+			deltaValues(childPath, valueA, valueB, changes)
+		}
+
+		for key := range convertedB {
+			if _, ok := convertedA[key]; !ok {
+				changes.Added = append(changes.Added, path+"/"+escapePointerToken(key))
+			}
+		}
+
+		return
+	case []any:
+		convertedB, ok := b.([]any)
+		if !ok {
+			break
+		}
+
+		common := min(len(convertedA), len(convertedB))
+		for i := range common {
+			deltaValues(path+"/"+strconv.Itoa(i), convertedA[i], convertedB[i], changes)
+		}
+
+		for i := common; i < len(convertedB); i++ {
+			changes.Added = append(changes.Added, path+"/"+strconv.Itoa(i))
+		}
+
+		for i := common; i < len(convertedA); i++ {
+			changes.Removed = append(changes.Removed, path+"/"+strconv.Itoa(i))
+		}
+
+		return
+	}
+
+	if !equalValues(a, b) {
+		changes.Changed = append(changes.Changed, path)
+	}
+}
+
+func apiEqual(L *lua.LState) int {
+	a := L.CheckAny(1)
+	b := L.CheckAny(2)
+
+	equal, err := Equal(a, b)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LBool(equal))
+
+	return 1
+}
+
+func apiDelta(L *lua.LState) int {
+	a := L.CheckAny(1)
+	b := L.CheckAny(2)
+
+	changes, err := Delta(a, b)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	pathList := func(paths []string) *lua.LTable {
+		list := L.CreateTable(len(paths), 0)
+		for _, path := range paths {
+			list.Append(lua.LString(path))
+		}
+
+		return MarkArray(L, list)
+	}
+
+	result := L.CreateTable(0, 3)
+	result.RawSetString("added", pathList(changes.Added))
+	result.RawSetString("removed", pathList(changes.Removed))
+	result.RawSetString("changed", pathList(changes.Changed))
+
+	L.Push(result)
+
+	return 1
+}
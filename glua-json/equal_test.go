@@ -0,0 +1,123 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestEqualLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.equal) == "function")
+	assert(type(json.delta) == "function")
+
+	local live = json.decode('{"spec":{"replicas":3,"ratio":0.5,"ports":[80,443]},"kind":"Service"}')
+	local desired = {kind = "Service", spec = {ports = {80, 443}, ratio = 0.50, replicas = 3.0}}
+
+	-- Test equality
+	assert(json.equal(live, desired))
+	assert(json.equal(json.decode('{"b":1,"a":2}', {preserveOrder = true}), {a = 2, b = 1}))
+	assert(json.equal(json.decode("12345678901234567891", {useNumber = true}), 12345678901234567891) == false)
+	assert(json.equal(json.decode("9007199254740993", {useNumber = true}), json.decode("9007199254740993", {useNumber = true})))
+	assert(not json.equal({}, json.object()))
+	assert(not json.equal({1, 2}, {2, 1}))
+	assert(json.equal(nil, json.null))
+
+	-- Test delta
+	desired.spec.replicas = 4
+	desired.spec.ports = {80}
+	desired.spec.ratio = nil
+	desired.metadata = {name = "web"}
+	local changes = json.delta(live, desired)
+	assert(json.encode(changes.added) == '["/metadata"]', json.encode(changes.added))
+	assert(json.encode(changes.removed) == '["/spec/ports/1","/spec/ratio"]', json.encode(changes.removed))
+	assert(json.encode(changes.changed) == '["/spec/replicas"]', json.encode(changes.changed))
+
+	local changes = json.delta(live, live)
+	assert(#changes.added == 0 and #changes.removed == 0 and #changes.changed == 0)
+	assert(json.encode(changes) == '{"added":[],"changed":[],"removed":[]}')
+
+	-- Test errors
+	local equal, err = json.equal({f = function() end}, {})
+	assert(equal == nil)
+	assert(err == "cannot encode function to JSON at .f", err)
+
+	local changes, err = json.delta({}, {1, nil, 3})
+	assert(changes == nil)
+	assert(err == "cannot encode sparse array", err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDelta(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected luajson.Changes
+	}{
+		{
+			name: "equal",
+			a:    `{"a":[1,{"b":null}]}`,
+			b:    `{"a":[1.0,{"b":null}]}`,
+		},
+		{
+			name:     "root",
+			a:        `1`,
+			b:        `"1"`,
+			expected: luajson.Changes{Changed: []string{""}},
+		},
+		{
+			name:     "type change",
+			a:        `{"a":{"b":1}}`,
+			b:        `{"a":[1]}`,
+			expected: luajson.Changes{Changed: []string{"/a"}},
+		},
+		{
+			name:     "escaped keys",
+			a:        `{"a/b":1,"c~d":2}`,
+			b:        `{"a/b":2,"e":3}`,
+			expected: luajson.Changes{Added: []string{"/e"}, Removed: []string{"/c~0d"}, Changed: []string{"/a~1b"}},
+		},
+		{
+			name:     "array growth",
+			a:        `[1]`,
+			b:        `[2,3,4]`,
+			expected: luajson.Changes{Added: []string{"/1", "/2"}, Changed: []string{"/0"}},
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := luajson.DecodeOptions{PreserveNull: true, MarkContainers: true}
+
+			a, err := luajson.DecodeWithOptions(L, []byte(tt.a), opts)
+			require.NoError(t, err)
+
+			b, err := luajson.DecodeWithOptions(L, []byte(tt.b), opts)
+			require.NoError(t, err)
+
+			changes, err := luajson.Delta(a, b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, changes)
+
+			equal, err := luajson.Equal(a, b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected.Added == nil && tt.expected.Removed == nil && tt.expected.Changed == nil, equal)
+		})
+	}
+}
@@ -3,8 +3,11 @@ module github.com/projectsveltos/lua-utils/glua-json
 go 1.25.5
 
 require (
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
 	github.com/stretchr/testify v1.11.1
 	github.com/yuin/gopher-lua v1.1.1
+	go.yaml.in/yaml/v3 v3.0.4
+	golang.org/x/text v0.30.0
 	sigs.k8s.io/yaml v1.6.0
 )
 
@@ -0,0 +1,35 @@
+package json
+
+import (
+	"encoding/json"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// setLuaDecodeHooks sets the hooks of opts to the objectHook and numberHook
+// functions of the decode options table tbl. Errors raised by the functions
+// propagate to the caller of the decoding function.
+func setLuaDecodeHooks(tbl *lua.LTable, opts *DecodeOptions) {
+	if fn, ok := tbl.RawGetString("objectHook").(*lua.LFunction); ok {
+		opts.ObjectHook = func(L *lua.LState, obj *lua.LTable) lua.LValue {
+			return callHook(L, fn, obj)
+		}
+	}
+
+	if fn, ok := tbl.RawGetString("numberHook").(*lua.LFunction); ok {
+		opts.NumberHook = func(L *lua.LState, number json.Number) lua.LValue {
+			return callHook(L, fn, lua.LString(number))
+		}
+	}
+}
+
+func callHook(L *lua.LState, fn *lua.LFunction, arg lua.LValue) lua.LValue {
+	L.Push(fn)
+	L.Push(arg)
+	L.Call(1, 1)
+
+	result := L.Get(-1)
+	L.Pop(1)
+
+	return result
+}
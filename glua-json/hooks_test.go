@@ -0,0 +1,95 @@
+package json_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestDecodeHooksLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+
+	-- Test the object hook sees objects innermost first
+	local seen = {}
+	local function objectHook(obj)
+		table.insert(seen, obj.name)
+		if obj.cpu then
+			return obj.cpu .. "/" .. obj.memory
+		end
+		return obj
+	end
+
+	local input = '{"name":"pod","containers":[{"name":"web","resources":{"name":"limits","cpu":"1","memory":"1Gi"}}]}'
+	local obj = json.decode(input, {objectHook = objectHook})
+	assert(obj.containers[1].resources == "1/1Gi")
+	assert(table.concat(seen, ",") == "limits,web,pod", table.concat(seen, ","))
+
+	-- Test the number hook receives the literal
+	local obj = json.decode('{"uid":12345678901234567891,"ratio":0.50,"count":3}', {
+		numberHook = function(literal) return "n:" .. literal end,
+	})
+	assert(obj.uid == "n:12345678901234567891")
+	assert(obj.ratio == "n:0.50")
+	assert(obj.count == "n:3")
+
+	-- Test hooks with other options and YAML
+	local obj = json.decode('{"b":{"x":1},"a":2}', {
+		preserveOrder = true,
+		objectHook = function(obj) obj.seen = true; return obj end,
+		numberHook = function(literal) return tonumber(literal) * 10 end,
+	})
+	assert(json.encode(obj) == '{"b":{"x":10,"seen":true},"a":20,"seen":true}', json.encode(obj))
+
+	local obj = json.fromYAML("size: 2\n", {numberHook = function(literal) return literal .. "Gi" end})
+	assert(obj.size == "2Gi")
+
+	-- Test errors propagate
+	local ok, err = pcall(json.decode, '{"a":1}', {objectHook = function() error("invalid object") end})
+	assert(not ok)
+	assert(string.find(err, "invalid object"), err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeWithHooks(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	opts := luajson.DecodeOptions{
+		ObjectHook: func(L *lua.LState, obj *lua.LTable) lua.LValue {
+			if kind := obj.RawGetString("kind"); kind != lua.LNil {
+				return kind
+			}
+
+			return obj
+		},
+		NumberHook: func(_ *lua.LState, number json.Number) lua.LValue {
+			return lua.LString(number)
+		},
+	}
+
+	value, err := luajson.DecodeWithOptions(L, []byte(`{"items":[{"kind":"Pod"},{"replicas":1e3}]}`), opts)
+	require.NoError(t, err)
+
+	data, err := luajson.Encode(value)
+	require.NoError(t, err)
+	assert.Equal(t, `{"items":["Pod",{"replicas":"1e3"}]}`, string(data))
+
+	value = luajson.DecodeValueWithOptions(L, map[string]any{"replicas": 2.5, "kind": "Pod"}, opts)
+	assert.Equal(t, lua.LString("Pod"), value)
+
+	value = luajson.DecodeValueWithOptions(L, []any{2.5}, opts)
+	assert.Equal(t, lua.LString("2.5"), value.(*lua.LTable).RawGetInt(1))
+}
@@ -1,8 +1,15 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
 
 	lua "github.com/yuin/gopher-lua"
 	"sigs.k8s.io/yaml"
@@ -16,7 +23,16 @@ func (i invalidTypeError) Error() string {
 
 type jsonValue struct {
 	lua.LValue
+	state *encodeState
+}
+
+// encodeState is shared by the jsonValues of a single encoding.
+type encodeState struct {
+	opts    EncodeOptions
 	visited map[*lua.LTable]bool
+	// emptyObjects converts unmarked empty tables outside of arrays to empty
+	// objects rather than arrays.
+	emptyObjects bool
 }
 
 var (
@@ -25,94 +41,458 @@ var (
 	errInvalidKeys = errors.New("cannot encode mixed or invalid key types")
 )
 
-// Encode returns the JSON encoding of value.
+// luaIdentifier matches the keys a path can refer to with a dot.
+var luaIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// EncodeError reports a value that could not be encoded and where it is.
+type EncodeError struct {
+	// Path locates the value from the top-level value, such as
+	// .spec.containers[3].ports, with Lua's one-based array indices. Keys that
+	// are not identifiers are written as ["key"]. It is empty for the
+	// top-level value.
+	Path string
+	Err  error
+}
+
+func (e *EncodeError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+
+	return e.Err.Error() + " at " + e.Path
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// withPathSegment prepends segment to the path of the encode error err.
+func withPathSegment(err error, segment string) error {
+	var encodeErr *EncodeError
+	if errors.As(err, &encodeErr) {
+		encodeErr.Path = segment + encodeErr.Path
+
+		return encodeErr
+	}
+
+	return &EncodeError{Path: segment, Err: err}
+}
+
+func keySegment(key string) string {
+	if luaIdentifier.MatchString(key) {
+		return "." + key
+	}
+
+	return fmt.Sprintf("[%q]", key)
+}
+
+// SparseArrayPolicy selects how tables whose numeric keys are not the
+// sequence 1..n are encoded.
+type SparseArrayPolicy int
+
+const (
+	// SparseArrayError fails the encoding.
+	SparseArrayError SparseArrayPolicy = iota
+	// SparseArrayNull encodes an array up to the largest index, with null in
+	// place of the missing elements. Like lua-cjson, it fails arrays larger
+	// than 10 elements where more than half would be null.
+	SparseArrayNull
+	// SparseArrayObject encodes an object, with the numeric keys converted to
+	// strings.
+	SparseArrayObject
+)
+
+// MixedKeysPolicy selects how tables holding both numeric and string keys,
+// and tables marked as objects holding numeric keys, are encoded.
+type MixedKeysPolicy int
+
+const (
+	// MixedKeysError fails the encoding.
+	MixedKeysError MixedKeysPolicy = iota
+	// MixedKeysObject encodes an object, with the numeric keys converted to
+	// strings.
+	MixedKeysObject
+)
+
+// EncodeOptions controls how Lua values are encoded to JSON.
+type EncodeOptions struct {
+	// Prefix and Indent pretty-print the output as json.Indent does when
+	// either is set.
+	Prefix string
+	Indent string
+	// EscapeHTML escapes <, > and & in strings, as encoding/json does.
+	EscapeHTML bool
+	// SortKeys writes object keys in sorted order. Otherwise they are written
+	// in the iteration order of the table. Tables marked with MarkOrdered
+	// always keep their order.
+	SortKeys bool
+	// SparseArrays selects how sparse arrays are encoded. Defaults to
+	// SparseArrayError.
+	SparseArrays SparseArrayPolicy
+	// MixedKeys selects how tables mixing numeric and string keys are
+	// encoded. Defaults to MixedKeysError.
+	MixedKeys MixedKeysPolicy
+	// State is used to call the __tojson metamethods of tables and userdata,
+	// whose return value is encoded in their place. They are not called when
+	// State is nil.
+	State *lua.LState
+}
+
+// Encode returns the JSON encoding of value. Object keys are sorted and HTML
+// characters in strings are escaped.
 func Encode(value lua.LValue) ([]byte, error) {
-	return json.Marshal(jsonValue{
-		LValue:  value,
+	return EncodeWithOptions(value, EncodeOptions{EscapeHTML: true, SortKeys: true})
+}
+
+// EncodeWithOptions returns the JSON encoding of value according to opts.
+// Errors are *EncodeError values locating the offending value.
+func EncodeWithOptions(value lua.LValue, opts EncodeOptions) ([]byte, error) {
+	state := &encodeState{
+		opts:    opts,
 		visited: make(map[*lua.LTable]bool),
-	})
+	}
+
+	data, err := jsonValue{value, state}.MarshalJSON()
+	if err != nil {
+		return nil, withPathSegment(err, "")
+	}
+
+	if opts.Prefix == "" && opts.Indent == "" {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+
+	err = json.Indent(&buf, data, opts.Prefix, opts.Indent)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
 }
 
 func (j jsonValue) MarshalJSON() (data []byte, err error) {
+	if data, ok, err := j.marshalCustom(); ok {
+		return data, err
+	}
+
 	switch converted := j.LValue.(type) {
 	case lua.LBool:
 		data, err = json.Marshal(bool(converted))
 	case lua.LNumber:
-		data, err = json.Marshal(float64(converted))
+		data, err = encodeNumber(converted)
 	case *lua.LNilType:
 		data = []byte(`null`)
 	case lua.LString:
-		data, err = json.Marshal(string(converted))
-	case *lua.LTable:
-		if j.visited[converted] {
-			return nil, errNested
+		data, err = encodeString(string(converted), j.state.opts.EscapeHTML)
+	case *lua.LUserData:
+		if IsNull(converted) {
+			data = []byte(`null`)
+		} else if number, ok := largeNumber(converted); ok {
+			data = []byte(number)
+		} else {
+			err = invalidTypeError(j.LValue.Type())
 		}
+	case *lua.LTable:
+		data, err = j.marshalTable(converted)
+	default:
+		err = invalidTypeError(j.LValue.Type())
+	}
+
+	return data, err
+}
 
-		j.visited[converted] = true
+// tableShape is the JSON layout of a table: an array of length elements, or
+// an object with the given keys.
+type tableShape struct {
+	array  bool
+	length int
+	keys   []lua.LValue
+}
+
+// shape returns the layout of tbl, which is an array when its keys are numeric
+// and an object when they are strings, unless it is marked otherwise. Other
+// tables are laid out according to the SparseArrays and MixedKeys options.
+func (s *encodeState) shape(tbl *lua.LTable) (tableShape, error) {
+	if s.visited[tbl] {
+		return tableShape{}, errNested
+	}
+
+	s.visited[tbl] = true
 
-		key, value := converted.Next(lua.LNil)
+	var keys []lua.LValue
 
+	numbers := 0
+
+	for key, _ := tbl.Next(lua.LNil); key != lua.LNil; key, _ = tbl.Next(key) {
 		switch key.Type() {
-		case lua.LTNil: // empty table
-			data = []byte(`[]`)
 		case lua.LTNumber:
-			arr := make([]jsonValue, 0, converted.Len())
-			expectedKey := lua.LNumber(1)
+			numbers++
+		case lua.LTString:
+		default:
+			return tableShape{}, errInvalidKeys
+		}
 
-			for key != lua.LNil {
-				if key.Type() != lua.LTNumber {
-					err = errInvalidKeys
+		keys = append(keys, key)
+	}
 
-					return data, err
-				}
+	jsonType := tableType(tbl)
+
+	switch {
+	case len(keys) == 0:
+		return tableShape{array: jsonType != jsonTypeObject}, nil
+	case numbers == len(keys) && jsonType != jsonTypeObject:
+		return s.arrayShape(keys)
+	case jsonType == jsonTypeArray:
+		return tableShape{}, errInvalidKeys
+	case numbers > 0 && s.opts.MixedKeys != MixedKeysObject:
+		return tableShape{}, errInvalidKeys
+	}
 
-				if expectedKey != key {
-					err = errSparseArray
+	return tableShape{keys: keys}, nil
+}
 
-					return data, err
-				}
+// sparseArraySafe and sparseArrayRatio bound the arrays SparseArrayNull fills
+// with null: up to sparseArraySafe elements, or up to sparseArrayRatio times
+// as many elements as the table holds.
+const (
+	sparseArraySafe  = 10
+	sparseArrayRatio = 2
+)
 
-				arr = append(arr, jsonValue{value, j.visited})
-				expectedKey++
-				key, value = converted.Next(key)
-			}
+// arrayShape returns the layout of a table whose keys are all numeric.
+func (s *encodeState) arrayShape(keys []lua.LValue) (tableShape, error) {
+	length := len(keys)
+	maxIndex := 0
+	integral := true
 
-			data, err = json.Marshal(arr)
-		case lua.LTString:
-			obj := make(map[string]jsonValue)
+	for _, key := range keys {
+		f := float64(key.(lua.LNumber))
+		if f != math.Trunc(f) || f < 1 || f > math.MaxInt32 {
+			integral = false
 
-			for key != lua.LNil {
-				if key.Type() != lua.LTString {
-					err = errInvalidKeys
+			break
+		}
 
-					return data, err
-				}
+		maxIndex = max(maxIndex, int(f))
+	}
 
-				obj[key.String()] = jsonValue{value, j.visited}
-				key, value = converted.Next(key)
-			}
+	if integral && maxIndex == length {
+		return tableShape{array: true, length: length}, nil
+	}
 
-			data, err = json.Marshal(obj)
-		default:
-			err = errInvalidKeys
+	switch s.opts.SparseArrays {
+	case SparseArrayNull:
+		if !integral || (maxIndex > sparseArraySafe && maxIndex > length*sparseArrayRatio) {
+			return tableShape{}, errSparseArray
 		}
+
+		return tableShape{array: true, length: maxIndex}, nil
+	case SparseArrayObject:
+		return tableShape{keys: keys}, nil
 	default:
-		err = invalidTypeError(j.LValue.Type())
+		return tableShape{}, errSparseArray
 	}
+}
 
-	return data, err
+// objectEntries returns the names of the keys of tbl, converting numeric keys
+// to strings, and the values they hold.
+func objectEntries(tbl *lua.LTable, keys []lua.LValue) ([]string, map[string]lua.LValue, error) {
+	names := make([]string, len(keys))
+	values := make(map[string]lua.LValue, len(keys))
+
+	for i, key := range keys {
+		names[i] = key.String()
+
+		if _, ok := values[names[i]]; ok {
+			// A numeric key and a string key convert to the same name.
+			return nil, nil, errInvalidKeys
+		}
+
+		values[names[i]] = tbl.RawGet(key)
+	}
+
+	return names, values, nil
+}
+
+// marshalTable returns the JSON encoding of tbl, laid out as shape returns.
+func (j jsonValue) marshalTable(tbl *lua.LTable) ([]byte, error) {
+	shape, err := j.state.shape(tbl)
+	if err != nil {
+		return nil, err
+	}
+
+	if shape.array {
+		return j.marshalArray(tbl, shape.length)
+	}
+
+	return j.marshalObject(tbl, shape.keys)
+}
+
+// marshalArray returns the JSON encoding of the first length elements of tbl.
+func (j jsonValue) marshalArray(tbl *lua.LTable, length int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('[')
+
+	for i := 1; i <= length; i++ {
+		if i > 1 {
+			buf.WriteByte(',')
+		}
+
+		item, err := jsonValue{tbl.RawGetInt(i), j.state}.MarshalJSON()
+		if err != nil {
+			return nil, withPathSegment(err, fmt.Sprintf("[%d]", i))
+		}
+
+		buf.Write(item)
+	}
+
+	buf.WriteByte(']')
+
+	return buf.Bytes(), nil
+}
+
+// marshalObject returns the JSON encoding of tbl as an object with keys.
+func (j jsonValue) marshalObject(tbl *lua.LTable, keys []lua.LValue) ([]byte, error) {
+	names, values, err := objectEntries(tbl, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isOrderedTable(tbl) && j.state.opts.SortKeys {
+		sort.Strings(names)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := encodeString(name, j.state.opts.EscapeHTML)
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := jsonValue{values[name], j.state}.MarshalJSON()
+		if err != nil {
+			return nil, withPathSegment(err, keySegment(name))
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(item)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// encodeString returns the JSON encoding of str.
+func encodeString(str string, escapeHTML bool) ([]byte, error) {
+	return encodeGoValue(str, escapeHTML)
+}
+
+// encodeGoValue returns the JSON encoding of value, as json.Marshal does, but
+// escaping HTML characters only if escapeHTML is set.
+func encodeGoValue(value any, escapeHTML bool) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(escapeHTML)
+
+	err := encoder.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// DecodeOptions controls how decoded values are converted to Lua values.
+type DecodeOptions struct {
+	// PreserveNull converts JSON null to the Null sentinel instead of nil, so
+	// that object keys holding null are kept.
+	PreserveNull bool
+	// MarkContainers marks decoded objects and arrays with MarkObject and
+	// MarkArray, so that they keep their JSON type when encoded, even once
+	// emptied.
+	MarkContainers bool
+	// UseNumber decodes numbers without going through float64. Integers up to
+	// 2^53 in magnitude and non-integral numbers become Lua numbers; larger
+	// integers become userdata that keep their exact digits, compare with each
+	// other and encode back unchanged.
+	UseNumber bool
+	// PreserveOrder inserts object keys in the order they appear in the input
+	// and marks objects with MarkOrdered, so that Encode and ToYAML reproduce
+	// that order. It has no effect on DecodeValueWithOptions, as Go maps are
+	// unordered.
+	PreserveOrder bool
+	// ObjectHook, when set, is called with each decoded object, after the
+	// values it holds, and its return value replaces the object.
+	ObjectHook func(L *lua.LState, obj *lua.LTable) lua.LValue
+	// NumberHook, when set, is called with the literal of each decoded number
+	// and its return value replaces the number. It takes precedence over
+	// UseNumber.
+	NumberHook func(L *lua.LState, number json.Number) lua.LValue
+	// Limits bounds the input that is accepted.
+	Limits Limits
 }
 
 // Decode converts the JSON encoded data to Lua values.
 func Decode(L *lua.LState, data []byte) (lua.LValue, error) {
+	return DecodeWithOptions(L, data, DecodeOptions{})
+}
+
+// DecodeWithOptions converts the JSON encoded data to Lua values according to
+// opts.
+func DecodeWithOptions(L *lua.LState, data []byte, opts DecodeOptions) (lua.LValue, error) {
+	err := opts.Limits.check(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.PreserveOrder {
+		return decodeOrdered(L, data, opts)
+	}
+
+	value, err := unmarshal(data, opts.UseNumber || opts.NumberHook != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeValueWithOptions(L, value, opts), nil
+}
+
+// unmarshal decodes data like json.Unmarshal into an any, using json.Number for
+// numbers when useNumber is set.
+func unmarshal(data []byte, useNumber bool) (any, error) {
 	var value any
 
-	err := json.Unmarshal(data, &value)
+	if !useNumber {
+		err := json.Unmarshal(data, &value)
+
+		return value, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	err := decoder.Decode(&value)
 	if err != nil {
 		return nil, err
 	}
 
-	return DecodeValue(L, value), nil
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid data after top-level value")
+	}
+
+	return value, nil
 }
 
 // DecodeValue converts the value to a Lua value.
@@ -120,30 +500,66 @@ func Decode(L *lua.LState, data []byte) (lua.LValue, error) {
 // This function only converts values that the encoding/json package decodes to.
 // All other values will return lua.LNil.
 func DecodeValue(L *lua.LState, value any) lua.LValue {
+	return DecodeValueWithOptions(L, value, DecodeOptions{})
+}
+
+// DecodeValueWithOptions converts the value to a Lua value according to opts.
+//
+// This function only converts values that the encoding/json package decodes to.
+// All other values will return lua.LNil.
+func DecodeValueWithOptions(L *lua.LState, value any, opts DecodeOptions) lua.LValue {
 	switch converted := value.(type) {
 	case bool:
 		return lua.LBool(converted)
 	case float64:
+		if opts.NumberHook != nil {
+			return opts.NumberHook(L, json.Number(strconv.FormatFloat(converted, 'g', -1, 64)))
+		}
+
 		return lua.LNumber(converted)
 	case string:
 		return lua.LString(converted)
 	case json.Number:
+		if opts.NumberHook != nil {
+			return opts.NumberHook(L, converted)
+		}
+
+		if opts.UseNumber {
+			return decodeNumber(L, converted)
+		}
+
 		return lua.LString(converted)
 	case []any:
 		arr := L.CreateTable(len(converted), 0)
 		for _, item := range converted {
-			arr.Append(DecodeValue(L, item))
+			arr.Append(DecodeValueWithOptions(L, item, opts))
+		}
+
+		if opts.MarkContainers {
+			MarkArray(L, arr)
 		}
 
 		return arr
 	case map[string]any:
 		tbl := L.CreateTable(0, len(converted))
 		for key, item := range converted {
-			tbl.RawSetH(lua.LString(key), DecodeValue(L, item))
+			tbl.RawSetH(lua.LString(key), DecodeValueWithOptions(L, item, opts))
+		}
+
+		if opts.MarkContainers {
+			MarkObject(L, tbl)
+		}
+
+		if opts.ObjectHook != nil {
+			return opts.ObjectHook(L, tbl)
 		}
 
 		return tbl
 	case nil:
+		if opts.PreserveNull {
+			return Null(L)
+		}
+
 		return lua.LNil
 	}
 
@@ -152,28 +568,74 @@ func DecodeValue(L *lua.LState, value any) lua.LValue {
 
 // FromYAML converts the YAML encoded data to Lua values.
 func FromYAML(L *lua.LState, data []byte) (lua.LValue, error) {
-	jsonData, err := yaml.YAMLToJSON(data)
+	return FromYAMLWithOptions(L, data, DecodeOptions{})
+}
+
+// FromYAMLWithOptions converts the YAML encoded data to Lua values according
+// to opts.
+func FromYAMLWithOptions(L *lua.LState, data []byte, opts DecodeOptions) (lua.LValue, error) {
+	err := opts.Limits.checkSize(len(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// The size of the converted JSON is not limited, as it differs from the
+	// size of the input. Its depth and element count are.
+	opts.Limits.MaxBytes = 0
+
+	convert := yaml.YAMLToJSON
+	if opts.PreserveOrder {
+		convert = yamlToOrderedJSON
+	}
+
+	jsonData, err := convert(data)
 	if err != nil {
 		return nil, err
 	}
 
-	return Decode(L, jsonData)
+	return DecodeWithOptions(L, jsonData, opts)
 }
 
 // ToYAML returns the YAML encoding of value.
 func ToYAML(value lua.LValue) ([]byte, error) {
-	jsonData, err := Encode(value)
+	return toYAML(value, EncodeOptions{EscapeHTML: true, SortKeys: true})
+}
+
+// toYAML returns the YAML encoding of value, encoded to JSON according to
+// opts first.
+func toYAML(value lua.LValue, opts EncodeOptions) ([]byte, error) {
+	jsonData, err := EncodeWithOptions(value, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return yaml.JSONToYAML(jsonData)
+	return jsonToYAML(jsonData)
+}
+
+// checkDecodeOptions reads the optional decode options table at index n.
+func checkDecodeOptions(L *lua.LState, n int) DecodeOptions {
+	tbl := L.OptTable(n, nil)
+
+	opts := DecodeOptions{Limits: checkLimits(L, tbl)}
+	if tbl == nil {
+		return opts
+	}
+
+	opts.PreserveNull = lua.LVAsBool(tbl.RawGetString("preserveNull"))
+	opts.MarkContainers = lua.LVAsBool(tbl.RawGetString("markContainers"))
+	opts.UseNumber = lua.LVAsBool(tbl.RawGetString("useNumber"))
+	opts.PreserveOrder = lua.LVAsBool(tbl.RawGetString("preserveOrder"))
+
+	setLuaDecodeHooks(tbl, &opts)
+
+	return opts
 }
 
 func apiDecode(L *lua.LState) int {
 	str := L.CheckString(1)
+	opts := checkDecodeOptions(L, 2)
 
-	value, err := Decode(L, []byte(str))
+	value, err := DecodeWithOptions(L, []byte(str), opts)
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(err.Error()))
@@ -186,10 +648,57 @@ func apiDecode(L *lua.LState) int {
 	return 1
 }
 
+// checkEncodeOptions reads the optional encode options table at index n.
+func checkEncodeOptions(L *lua.LState, n int) EncodeOptions {
+	opts := EncodeOptions{EscapeHTML: true, SortKeys: true, State: L}
+
+	tbl := L.OptTable(n, nil)
+	if tbl == nil {
+		return opts
+	}
+
+	if prefix, ok := tbl.RawGetString("prefix").(lua.LString); ok {
+		opts.Prefix = string(prefix)
+	}
+
+	if indent, ok := tbl.RawGetString("indent").(lua.LString); ok {
+		opts.Indent = string(indent)
+	}
+
+	if escapeHTML, ok := tbl.RawGetString("escapeHTML").(lua.LBool); ok {
+		opts.EscapeHTML = bool(escapeHTML)
+	}
+
+	if sortKeys, ok := tbl.RawGetString("sortKeys").(lua.LBool); ok {
+		opts.SortKeys = bool(sortKeys)
+	}
+
+	switch policy := tbl.RawGetString("sparseArrays"); policy {
+	case lua.LNil, lua.LString("error"):
+	case lua.LString("null"):
+		opts.SparseArrays = SparseArrayNull
+	case lua.LString("object"):
+		opts.SparseArrays = SparseArrayObject
+	default:
+		L.ArgError(n, "invalid sparseArrays policy "+policy.String())
+	}
+
+	switch policy := tbl.RawGetString("mixedKeys"); policy {
+	case lua.LNil, lua.LString("error"):
+	case lua.LString("object"):
+		opts.MixedKeys = MixedKeysObject
+	default:
+		L.ArgError(n, "invalid mixedKeys policy "+policy.String())
+	}
+
+	return opts
+}
+
 func apiEncode(L *lua.LState) int {
 	value := L.CheckAny(1)
+	opts := checkEncodeOptions(L, 2)
 
-	data, err := Encode(value)
+	data, err := EncodeWithOptions(value, opts)
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(err.Error()))
@@ -204,16 +713,9 @@ func apiEncode(L *lua.LState) int {
 
 func apiFromYAML(L *lua.LState) int {
 	str := L.CheckString(1)
+	opts := checkDecodeOptions(L, 2)
 
-	jsonData, err := yaml.YAMLToJSON([]byte(str))
-	if err != nil {
-		L.Push(lua.LNil)
-		L.Push(lua.LString(err.Error()))
-
-		return 2
-	}
-
-	value, err := Decode(L, jsonData)
+	value, err := FromYAMLWithOptions(L, []byte(str), opts)
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(err.Error()))
@@ -229,15 +731,7 @@ func apiFromYAML(L *lua.LState) int {
 func apiToYAML(L *lua.LState) int {
 	value := L.CheckAny(1)
 
-	jsonData, err := Encode(value)
-	if err != nil {
-		L.Push(lua.LNil)
-		L.Push(lua.LString(err.Error()))
-
-		return 2
-	}
-
-	yamlData, err := yaml.JSONToYAML(jsonData)
+	yamlData, err := toYAML(value, EncodeOptions{EscapeHTML: true, SortKeys: true, State: L})
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(err.Error()))
@@ -251,17 +745,61 @@ func apiToYAML(L *lua.LState) int {
 }
 
 var api = map[string]lua.LGFunction{
-	"decode":   apiDecode,
-	"encode":   apiEncode,
-	"fromYAML": apiFromYAML,
-	"toYAML":   apiToYAML,
+	"array":               apiArray,
+	"canonical":           apiCanonical,
+	"decode":              apiDecode,
+	"decodeLines":         apiDecodeLines,
+	"delta":               apiDelta,
+	"diff":                apiDiff,
+	"encode":              apiEncode,
+	"encodeLines":         apiEncodeLines,
+	"equal":               apiEqual,
+	"fromYAML":            apiFromYAML,
+	"fromYAMLAll":         apiFromYAMLAll,
+	"jsonpath":            apiJSONPath,
+	"lines":               apiLines,
+	"mergePatch":          apiMergePatch,
+	"object":              apiObject,
+	"ordered":             apiOrdered,
+	"pairs":               apiPairs,
+	"patch":               apiPatch,
+	"strategicMergePatch": apiStrategicMergePatch,
+	"toYAML":              apiToYAML,
+	"toYAMLAll":           apiToYAMLAll,
 }
 
-// Loader is the module loader function.
+// Loader is the module loader function. Decoding is not limited.
 func Loader(L *lua.LState) int {
-	t := L.NewTable()
+	return load(L, Limits{})
+}
+
+// LoaderWithLimits returns a module loader function whose decoding functions
+// enforce limits. Scripts can lower the limits through decode options, but
+// not raise them.
+func LoaderWithLimits(limits Limits) lua.LGFunction {
+	return func(L *lua.LState) int {
+		return load(L, limits)
+	}
+}
 
-	L.SetFuncs(t, api)
+func load(L *lua.LState, limits Limits) int {
+	t := L.NewTable()
+	pointer := L.NewTable()
+	yaml := L.NewTable()
+	schema := L.NewTable()
+
+	// The limits are an upvalue of the functions, read by checkLimits.
+	ud := L.NewUserData()
+	ud.Value = limits
+
+	L.SetFuncs(t, api, ud)
+	L.SetFuncs(pointer, pointerAPI)
+	L.SetFuncs(yaml, yamlAPI, ud)
+	L.SetFuncs(schema, schemaAPI)
+	t.RawSetString("pointer", pointer)
+	t.RawSetString("yaml", yaml)
+	t.RawSetString("schema", schema)
+	t.RawSetString("null", Null(L))
 	L.Push(t)
 
 	return 1
@@ -274,3 +812,9 @@ func Loader(L *lua.LState) int {
 func Preload(L *lua.LState) {
 	L.PreloadModule("json", Loader)
 }
+
+// PreloadWithLimits is like Preload, with the decoding functions of the module
+// enforcing limits, such as DefaultLimits.
+func PreloadWithLimits(L *lua.LState, limits Limits) {
+	L.PreloadModule("json", LoaderWithLimits(limits))
+}
@@ -450,6 +450,17 @@ func TestToYAML(t *testing.T) {
 			},
 			contains: []string{"inner:", "count: 5"},
 		},
+		{
+			name: "large numbers",
+			input: func(L *lua.LState) lua.LValue {
+				value, err := luajson.DecodeWithOptions(L, []byte(`{"big":123456789012345678901234567890,"x":1e21}`), luajson.DecodeOptions{UseNumber: true})
+				if err != nil {
+					panic(err)
+				}
+				return value
+			},
+			contains: []string{"big: 123456789012345678901234567890\n", "x: 1000000000000000000000\n"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -474,6 +485,29 @@ func TestToYAML(t *testing.T) {
 	}
 }
 
+func TestToYAMLBooleanStrings(t *testing.T) {
+	const str = `
+	local json = require("json")
+	local words = {"yes", "on", "y", "no", "off", "n", "Off", "true"}
+	local value = {}
+	for _, word in ipairs(words) do
+		value[word] = word
+	end
+
+	local out = json.toYAML(value)
+	assert(string.find(out, '"no": "no"', 1, true), out)
+	assert(json.encode(json.fromYAML(out)) == json.encode(value), out)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestDecode(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -567,3 +601,288 @@ func TestDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeOptionsLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	local obj = {url = "https://example.com/?a=1&b=<2>", list = {1, 2}}
+
+	-- Test defaults are unchanged
+	assert(json.encode(obj) == '{"list":[1,2],"url":"https://example.com/?a=1\\u0026b=\\u003c2\\u003e"}')
+
+	-- Test HTML escaping can be disabled
+	assert(json.encode(obj, {escapeHTML = false}) == '{"list":[1,2],"url":"https://example.com/?a=1&b=<2>"}')
+
+	-- Test indentation
+	local expected = '{\n  "list": [\n    1,\n    2\n  ],\n  "url": "https://example.com/?a=1&b=<2>"\n}'
+	assert(json.encode(obj, {indent = "  ", escapeHTML = false}) == expected)
+
+	-- Test unsorted keys follow the table iteration order
+	local ordered = {}
+	ordered.zeta = 1
+	ordered.alpha = 2
+	local encoded = json.encode(ordered, {sortKeys = false})
+	local keys = {}
+	for key in pairs(ordered) do
+		table.insert(keys, string.format("%q", key) .. ":" .. ordered[key])
+	end
+	assert(encoded == "{" .. table.concat(keys, ",") .. "}", encoded)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeWithOptions(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	value, err := luajson.Decode(L, []byte(`{"b":"<x>","a":[1]}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		opts     luajson.EncodeOptions
+		expected string
+	}{
+		{
+			name:     "compact",
+			opts:     luajson.EncodeOptions{SortKeys: true},
+			expected: `{"a":[1],"b":"<x>"}`,
+		},
+		{
+			name:     "escape HTML",
+			opts:     luajson.EncodeOptions{SortKeys: true, EscapeHTML: true},
+			expected: `{"a":[1],"b":"\u003cx\u003e"}`,
+		},
+		{
+			name:     "prefix and indent",
+			opts:     luajson.EncodeOptions{SortKeys: true, Prefix: "#", Indent: "\t"},
+			expected: "{\n#\t\"a\": [\n#\t\t1\n#\t],\n#\t\"b\": \"<x>\"\n#}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := luajson.EncodeWithOptions(value, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestEncodeErrorPath(t *testing.T) {
+	const str = `
+	local json = require("json")
+
+	local obj = {spec = {containers = {{name = "a"}, {name = "b"}, {name = "c", ports = {[1] = 80, [3] = 443}}}}}
+	local data, err = json.encode(obj)
+	assert(data == nil)
+	assert(err == "cannot encode sparse array at .spec.containers[3].ports", err)
+
+	local data, err = json.toYAML({metadata = {labels = {["app.kubernetes.io/name"] = print}}})
+	assert(data == nil)
+	assert(err == 'cannot encode function to JSON at .metadata.labels["app.kubernetes.io/name"]', err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name     string
+		input    func() lua.LValue
+		expected string
+	}{
+		{
+			name:     "top-level value",
+			input:    func() lua.LValue { return s.NewFunction(nil) },
+			expected: "",
+		},
+		{
+			name: "array element",
+			input: func() lua.LValue {
+				tbl := s.NewTable()
+				tbl.Append(lua.LNumber(1))
+				tbl.Append(s.NewFunction(nil))
+
+				return tbl
+			},
+			expected: "[2]",
+		},
+		{
+			name: "mixed keys",
+			input: func() lua.LValue {
+				inner := s.NewTable()
+				inner.RawSetString("name", lua.LString("test"))
+				inner.RawSetInt(1, lua.LNumber(42))
+
+				tbl := s.NewTable()
+				tbl.RawSetString("_inner1", inner)
+
+				return tbl
+			},
+			expected: "._inner1",
+		},
+		{
+			name: "nested tables",
+			input: func() lua.LValue {
+				tbl := s.NewTable()
+				tbl.RawSetString("self", tbl)
+
+				return tbl
+			},
+			expected: ".self",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := luajson.Encode(tt.input())
+			require.Error(t, err)
+
+			var encodeErr *luajson.EncodeError
+			require.ErrorAs(t, err, &encodeErr)
+			assert.Equal(t, tt.expected, encodeErr.Path)
+		})
+	}
+}
+
+func TestEncodePoliciesLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+
+	-- Test dropping entries with tbl[i] = nil
+	local items = {"a", "b", "c"}
+	items[2] = nil
+	assert(json.encode(items) == nil)
+	assert(json.encode(items, {sparseArrays = "error"}) == nil)
+	assert(json.encode(items, {sparseArrays = "null"}) == '["a",null,"c"]')
+	assert(json.encode(items, {sparseArrays = "object"}) == '{"1":"a","3":"c"}')
+
+	-- Test excessively sparse arrays
+	assert(json.encode({[10] = 1}, {sparseArrays = "null"}) == "[null,null,null,null,null,null,null,null,null,1]")
+	assert(json.encode({1, 2, 3, [6] = 6, [12] = 12}, {sparseArrays = "null"}) == nil)
+	local out, err = json.encode({[2e9] = 1}, {sparseArrays = "null"})
+	assert(out == nil)
+	assert(err == "cannot encode sparse array", err)
+
+	-- Test mixed keys
+	local mixed = {"a", name = "b"}
+	assert(json.encode(mixed) == nil)
+	assert(json.encode(mixed, {mixedKeys = "object"}) == '{"1":"a","name":"b"}')
+	assert(json.encode(json.object({"a"}), {mixedKeys = "object"}) == '{"1":"a"}')
+
+	-- Test invalid policies
+	assert(not pcall(json.encode, items, {sparseArrays = "compact"}))
+	assert(not pcall(json.encode, mixed, {mixedKeys = true}))`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeWithPolicies(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	sparse := L.NewTable()
+	sparse.RawSetInt(2, lua.LString("b"))
+	sparse.RawSetInt(4, lua.LString("d"))
+
+	fractional := L.NewTable()
+	fractional.RawSetInt(1, lua.LString("a"))
+	fractional.RawSet(lua.LNumber(1.5), lua.LString("b"))
+
+	mixed := L.NewTable()
+	mixed.RawSetInt(10, lua.LString("x"))
+	mixed.RawSetString("2", lua.LString("y"))
+
+	markedArray := luajson.MarkArray(L, L.NewTable())
+	markedArray.RawSetInt(1, lua.LString("x"))
+	markedArray.RawSetString("a", lua.LString("y"))
+
+	conflict := L.NewTable()
+	conflict.RawSetInt(1, lua.LString("x"))
+	conflict.RawSetString("1", lua.LString("y"))
+
+	tests := []struct {
+		name     string
+		input    lua.LValue
+		opts     luajson.EncodeOptions
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "sparse array with null",
+			input:    sparse,
+			opts:     luajson.EncodeOptions{SparseArrays: luajson.SparseArrayNull},
+			expected: `[null,"b",null,"d"]`,
+		},
+		{
+			name:     "sparse array as object",
+			input:    sparse,
+			opts:     luajson.EncodeOptions{SparseArrays: luajson.SparseArrayObject, SortKeys: true},
+			expected: `{"2":"b","4":"d"}`,
+		},
+		{
+			name:    "fractional key with null",
+			input:   fractional,
+			opts:    luajson.EncodeOptions{SparseArrays: luajson.SparseArrayNull},
+			wantErr: "cannot encode sparse array",
+		},
+		{
+			name:     "fractional key as object",
+			input:    fractional,
+			opts:     luajson.EncodeOptions{SparseArrays: luajson.SparseArrayObject, SortKeys: true},
+			expected: `{"1":"a","1.5":"b"}`,
+		},
+		{
+			name:     "mixed keys sorted as strings",
+			input:    mixed,
+			opts:     luajson.EncodeOptions{MixedKeys: luajson.MixedKeysObject, SortKeys: true},
+			expected: `{"10":"x","2":"y"}`,
+		},
+		{
+			name:    "conflicting keys",
+			input:   conflict,
+			opts:    luajson.EncodeOptions{MixedKeys: luajson.MixedKeysObject},
+			wantErr: "cannot encode mixed or invalid key types",
+		},
+		{
+			name:    "marked array",
+			input:   markedArray,
+			opts:    luajson.EncodeOptions{MixedKeys: luajson.MixedKeysObject},
+			wantErr: "cannot encode mixed or invalid key types",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := luajson.EncodeWithOptions(tt.input, tt.opts)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
@@ -0,0 +1,929 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var errInvalidJSONPath = errors.New("invalid JSONPath")
+
+// jsonPathStep transforms the current set of matches into the next one.
+type jsonPathStep func(values []lua.LValue) ([]lua.LValue, error)
+
+// jsonPathParser parses the kubectl flavour of JSONPath.
+type jsonPathParser struct {
+	expr string
+	pos  int
+}
+
+// QueryJSONPath evaluates the Kubernetes JSONPath expression expr, such as
+// {.status.conditions[?(@.type=="Ready")].status}, against value and returns
+// the matches in order. The surrounding braces and the leading $ are optional,
+// and several brace-delimited expressions may be given, in which case their
+// matches are concatenated. Array indices are zero-based, as in kubectl.
+//
+// range/end blocks are not supported.
+func QueryJSONPath(value lua.LValue, expr string) ([]lua.LValue, error) {
+	exprs, err := splitJSONPathTemplate(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []lua.LValue
+
+	for _, expr := range exprs {
+		steps, err := parseJSONPath(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := evalJSONPath([]lua.LValue{value}, steps)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, result...)
+	}
+
+	return matches, nil
+}
+
+// splitJSONPathTemplate returns the expressions between braces in template, or
+// template itself if it has no braces.
+func splitJSONPathTemplate(template string) ([]string, error) {
+	template = strings.TrimSpace(template)
+	if !strings.HasPrefix(template, "{") {
+		return []string{template}, nil
+	}
+
+	var exprs []string
+
+	for template != "" {
+		if template[0] != '{' {
+			return nil, fmt.Errorf("%w: unexpected text %q outside of braces", errInvalidJSONPath, template)
+		}
+
+		end := closingBrace(template)
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed brace", errInvalidJSONPath)
+		}
+
+		expr := strings.TrimSpace(template[1:end])
+		if expr == "end" || strings.HasPrefix(expr, "range ") {
+			return nil, fmt.Errorf("%w: range is not supported", errInvalidJSONPath)
+		}
+
+		exprs = append(exprs, expr)
+		template = strings.TrimSpace(template[end+1:])
+	}
+
+	return exprs, nil
+}
+
+// closingBrace returns the index of the brace closing the one template starts
+// with, ignoring braces inside quoted strings.
+func closingBrace(template string) int {
+	var quote byte
+
+	for i := 1; i < len(template); i++ {
+		switch c := template[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '}':
+			return i
+		}
+	}
+
+	return -1
+}
+
+func parseJSONPath(expr string) ([]jsonPathStep, error) {
+	if len(expr) > 1 && (expr[0] == '"' || expr[0] == '\'') {
+		literal, err := unquoteJSONPath(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		return []jsonPathStep{func([]lua.LValue) ([]lua.LValue, error) {
+			return []lua.LValue{lua.LString(literal)}, nil
+		}}, nil
+	}
+
+	p := &jsonPathParser{expr: expr}
+	if p.peek() == '$' || p.peek() == '@' {
+		p.pos++
+	}
+
+	steps, err := p.parseSteps()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.expr) {
+		return nil, p.errorf("unexpected character %q", p.expr[p.pos])
+	}
+
+	return steps, nil
+}
+
+func (p *jsonPathParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w %q at offset %d: %s", errInvalidJSONPath, p.expr, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonPathParser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+
+	return 0
+}
+
+func (p *jsonPathParser) skipSpaces() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+// parseSteps parses the steps of a path until the end of the expression or a
+// character that cannot continue a path.
+func (p *jsonPathParser) parseSteps() ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+
+			recursive := false
+			if p.peek() == '.' {
+				p.pos++
+				recursive = true
+			}
+
+			var step jsonPathStep
+
+			switch {
+			case p.peek() == '*':
+				p.pos++
+				step = wildcardStep
+			case p.peek() == '[' && recursive:
+				var err error
+
+				step, err = p.parseBracket()
+				if err != nil {
+					return nil, err
+				}
+			default:
+				name := p.parseName()
+				if name == "" {
+					if !recursive && len(steps) == 0 && p.pos == len(p.expr) {
+						// A lone "." selects the root.
+						return steps, nil
+					}
+
+					return nil, p.errorf("expected field name")
+				}
+
+				step = fieldStep(name)
+			}
+
+			if recursive {
+				step = recursiveStep(step)
+			}
+
+			steps = append(steps, step)
+		case '[':
+			step, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+
+			steps = append(steps, step)
+		default:
+			return steps, nil
+		}
+	}
+}
+
+// parseName parses a dotted field name. A backslash escapes the next
+// character, which allows dots in names such as app\.kubernetes\.io/name.
+func (p *jsonPathParser) parseName() string {
+	var sb strings.Builder
+
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		if c == '\\' && p.pos+1 < len(p.expr) {
+			sb.WriteByte(p.expr[p.pos+1])
+			p.pos += 2
+
+			continue
+		}
+
+		if strings.IndexByte(".[]()=!<>&| '\"", c) >= 0 {
+			break
+		}
+
+		sb.WriteByte(c)
+		p.pos++
+	}
+
+	return sb.String()
+}
+
+func (p *jsonPathParser) parseBracket() (jsonPathStep, error) {
+	p.pos++ // [
+	p.skipSpaces()
+
+	var step jsonPathStep
+
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		step = wildcardStep
+	case c == '?':
+		var err error
+
+		step, err = p.parseFilter()
+		if err != nil {
+			return nil, err
+		}
+	case c == '\'' || c == '"':
+		var names []string
+
+		for {
+			name, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+
+			names = append(names, name)
+
+			p.skipSpaces()
+
+			if p.peek() != ',' {
+				break
+			}
+
+			p.pos++
+			p.skipSpaces()
+		}
+
+		step = fieldStep(names...)
+	default:
+		var err error
+
+		step, err = p.parseIndices()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.skipSpaces()
+
+	if p.peek() != ']' {
+		return nil, p.errorf("expected ]")
+	}
+
+	p.pos++
+
+	return step, nil
+}
+
+func (p *jsonPathParser) parseQuoted() (string, error) {
+	quote := p.peek()
+	start := p.pos
+
+	for p.pos++; p.pos < len(p.expr); p.pos++ {
+		switch p.expr[p.pos] {
+		case '\\':
+			p.pos++
+		case quote:
+			p.pos++
+
+			return unquoteJSONPath(p.expr[start:p.pos])
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func unquoteJSONPath(quoted string) (string, error) {
+	if quoted[0] == '\'' {
+		body := quoted[1 : len(quoted)-1]
+
+		return strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(body), nil
+	}
+
+	unquoted, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid string %s", errInvalidJSONPath, quoted)
+	}
+
+	return unquoted, nil
+}
+
+func (p *jsonPathParser) parseInt() (int, bool) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+
+	for p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+
+	n, err := strconv.Atoi(p.expr[start:p.pos])
+	if err != nil {
+		p.pos = start
+
+		return 0, false
+	}
+
+	return n, true
+}
+
+// parseIndices parses an index, a union of indices or a slice.
+func (p *jsonPathParser) parseIndices() (jsonPathStep, error) {
+	var bounds [3]*int
+
+	for part := range bounds {
+		p.skipSpaces()
+
+		if n, ok := p.parseInt(); ok {
+			bounds[part] = &n
+		}
+
+		p.skipSpaces()
+
+		if p.peek() != ':' {
+			if part == 0 {
+				if bounds[0] == nil {
+					return nil, p.errorf("expected index")
+				}
+
+				indices := []int{*bounds[0]}
+
+				for p.peek() == ',' {
+					p.pos++
+					p.skipSpaces()
+
+					n, ok := p.parseInt()
+					if !ok {
+						return nil, p.errorf("expected index")
+					}
+
+					indices = append(indices, n)
+					p.skipSpaces()
+				}
+
+				return indexStep(indices), nil
+			}
+
+			break
+		}
+
+		if part == 2 {
+			return nil, p.errorf("too many slice bounds")
+		}
+
+		p.pos++
+	}
+
+	return sliceStep(bounds[0], bounds[1], bounds[2]), nil
+}
+
+// parseFilter parses ?(@.path), ?(@.path op literal) and their combinations
+// with && and ||.
+func (p *jsonPathParser) parseFilter() (jsonPathStep, error) {
+	p.pos++ // ?
+	p.skipSpaces()
+
+	if p.peek() != '(' {
+		return nil, p.errorf("expected (")
+	}
+
+	p.pos++
+
+	predicate, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+
+	if p.peek() != ')' {
+		return nil, p.errorf("expected )")
+	}
+
+	p.pos++
+
+	return func(values []lua.LValue) ([]lua.LValue, error) {
+		items, err := children(values)
+		if err != nil {
+			return nil, err
+		}
+
+		var result []lua.LValue
+
+		for _, item := range items {
+			ok, err := predicate(item)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				result = append(result, item)
+			}
+		}
+
+		return result, nil
+	}, nil
+}
+
+type jsonPathPredicate func(item lua.LValue) (bool, error)
+
+func (p *jsonPathParser) parseOr() (jsonPathPredicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+
+	for strings.HasPrefix(p.expr[p.pos:], "||") {
+		p.pos += 2
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = orPredicate(left, right)
+
+		p.skipSpaces()
+	}
+
+	return left, nil
+}
+
+func orPredicate(left, right jsonPathPredicate) jsonPathPredicate {
+	return func(item lua.LValue) (bool, error) {
+		ok, err := left(item)
+		if err != nil || ok {
+			return ok, err
+		}
+
+		return right(item)
+	}
+}
+
+func (p *jsonPathParser) parseAnd() (jsonPathPredicate, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+
+	for strings.HasPrefix(p.expr[p.pos:], "&&") {
+		p.pos += 2
+
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+
+		left = andPredicate(left, right)
+
+		p.skipSpaces()
+	}
+
+	return left, nil
+}
+
+func andPredicate(left, right jsonPathPredicate) jsonPathPredicate {
+	return func(item lua.LValue) (bool, error) {
+		ok, err := left(item)
+		if err != nil || !ok {
+			return ok, err
+		}
+
+		return right(item)
+	}
+}
+
+func (p *jsonPathParser) parseComparison() (jsonPathPredicate, error) {
+	p.skipSpaces()
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+
+	var op string
+
+	for _, candidate := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(p.expr[p.pos:], candidate) {
+			op = candidate
+
+			break
+		}
+	}
+
+	if op == "" {
+		return func(item lua.LValue) (bool, error) {
+			values, err := left(item)
+
+			return len(values) > 0 && values[0] != lua.LNil && values[0] != lua.LFalse, err
+		}, nil
+	}
+
+	p.pos += len(op)
+	p.skipSpaces()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item lua.LValue) (bool, error) {
+		leftValues, err := left(item)
+		if err != nil || len(leftValues) == 0 {
+			return false, err
+		}
+
+		rightValues, err := right(item)
+		if err != nil || len(rightValues) == 0 {
+			return false, err
+		}
+
+		return compareJSONPath(leftValues[0], op, rightValues[0]), nil
+	}, nil
+}
+
+// parseOperand parses a relative path starting with @ or a literal.
+func (p *jsonPathParser) parseOperand() (func(item lua.LValue) ([]lua.LValue, error), error) {
+	switch c := p.peek(); {
+	case c == '@':
+		p.pos++
+
+		steps, err := p.parseSteps()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(item lua.LValue) ([]lua.LValue, error) {
+			values, err := evalJSONPath([]lua.LValue{item}, steps)
+			if err != nil {
+				// Items the path cannot be evaluated on, for example because
+				// of an out of bounds index, do not match.
+				return nil, nil
+			}
+
+			return values, nil
+		}, nil
+	case c == '\'' || c == '"':
+		str, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+
+		return literalOperand(lua.LString(str)), nil
+	case strings.HasPrefix(p.expr[p.pos:], "true"):
+		p.pos += len("true")
+
+		return literalOperand(lua.LTrue), nil
+	case strings.HasPrefix(p.expr[p.pos:], "false"):
+		p.pos += len("false")
+
+		return literalOperand(lua.LFalse), nil
+	default:
+		start := p.pos
+		for strings.IndexByte("+-.0123456789eE", p.peek()) >= 0 && p.pos < len(p.expr) {
+			p.pos++
+		}
+
+		n, err := strconv.ParseFloat(p.expr[start:p.pos], 64)
+		if err != nil {
+			p.pos = start
+
+			return nil, p.errorf("expected @, a string, a number or a boolean")
+		}
+
+		return literalOperand(lua.LNumber(n)), nil
+	}
+}
+
+func literalOperand(value lua.LValue) func(lua.LValue) ([]lua.LValue, error) {
+	return func(lua.LValue) ([]lua.LValue, error) {
+		return []lua.LValue{value}, nil
+	}
+}
+
+func compareJSONPath(left lua.LValue, op string, right lua.LValue) bool {
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+
+	var cmp int
+
+	switch l := left.(type) {
+	case lua.LNumber:
+		r, ok := right.(lua.LNumber)
+		if !ok {
+			return false
+		}
+
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case lua.LString:
+		r, ok := right.(lua.LString)
+		if !ok {
+			return false
+		}
+
+		cmp = strings.Compare(string(l), string(r))
+	default:
+		return false
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func evalJSONPath(values []lua.LValue, steps []jsonPathStep) ([]lua.LValue, error) {
+	var err error
+
+	for _, step := range steps {
+		values, err = step(values)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func fieldStep(names ...string) jsonPathStep {
+	return func(values []lua.LValue) ([]lua.LValue, error) {
+		var result []lua.LValue
+
+		for _, value := range values {
+			tbl, ok, err := jsonTable(value)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			for _, name := range names {
+				if child := tbl.RawGetString(name); child != lua.LNil {
+					result = append(result, child)
+				}
+			}
+		}
+
+		return result, nil
+	}
+}
+
+func wildcardStep(values []lua.LValue) ([]lua.LValue, error) {
+	return children(values)
+}
+
+// recursiveStep applies step to the values and all of their descendants.
+// Tables reachable more than once, such as those of a cycle, are only walked
+// the first time.
+func recursiveStep(step jsonPathStep) jsonPathStep {
+	return func(values []lua.LValue) ([]lua.LValue, error) {
+		var all []lua.LValue
+
+		visited := make(map[*lua.LTable]bool)
+
+		var walk func(value lua.LValue) error
+
+		walk = func(value lua.LValue) error {
+			if tbl, ok := value.(*lua.LTable); ok {
+				if visited[tbl] {
+					return nil
+				}
+
+				visited[tbl] = true
+			}
+
+			all = append(all, value)
+
+			items, err := children([]lua.LValue{value})
+			if err != nil {
+				return err
+			}
+
+			for _, child := range items {
+				err = walk(child)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		for _, value := range values {
+			err := walk(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return step(all)
+	}
+}
+
+func indexStep(indices []int) jsonPathStep {
+	return func(values []lua.LValue) ([]lua.LValue, error) {
+		var result []lua.LValue
+
+		for _, value := range values {
+			tbl, ok, err := jsonTable(value)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			length := tbl.Len()
+
+			for _, index := range indices {
+				if index < 0 {
+					index += length
+				}
+
+				if index < 0 || index >= length {
+					return nil, fmt.Errorf("array index out of bounds: index %d, length %d", index, length)
+				}
+
+				result = append(result, tbl.RawGetInt(index+1))
+			}
+		}
+
+		return result, nil
+	}
+}
+
+func sliceStep(start, end, step *int) jsonPathStep {
+	return func(values []lua.LValue) ([]lua.LValue, error) {
+		var result []lua.LValue
+
+		stride := 1
+		if step != nil {
+			stride = *step
+		}
+
+		if stride <= 0 {
+			return nil, fmt.Errorf("%w: slice step must be positive", errInvalidJSONPath)
+		}
+
+		for _, value := range values {
+			tbl, ok, err := jsonTable(value)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			length := tbl.Len()
+			from := clampSliceBound(start, 0, length)
+			to := clampSliceBound(end, length, length)
+
+			for i := from; i < to; i += stride {
+				result = append(result, tbl.RawGetInt(i+1))
+			}
+		}
+
+		return result, nil
+	}
+}
+
+func clampSliceBound(bound *int, def, length int) int {
+	if bound == nil {
+		return def
+	}
+
+	n := *bound
+	if n < 0 {
+		n += length
+	}
+
+	return max(0, min(n, length))
+}
+
+// jsonTable returns value if it is a table, or the table of its elements if
+// it is a proxy.
+func jsonTable(value lua.LValue) (*lua.LTable, bool, error) {
+	if p, ok := asProxy(value); ok {
+		tbl, err := p.table()
+
+		return tbl, err == nil, err
+	}
+
+	tbl, ok := value.(*lua.LTable)
+
+	return tbl, ok, nil
+}
+
+// children returns the elements of arrays and the values of objects, the
+// latter ordered by key.
+func children(values []lua.LValue) ([]lua.LValue, error) {
+	var result []lua.LValue
+
+	for _, value := range values {
+		tbl, ok, err := jsonTable(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		if !isObjectTable(tbl) {
+			for i := 1; i <= tbl.Len(); i++ {
+				result = append(result, tbl.RawGetInt(i))
+			}
+
+			continue
+		}
+
+		var keys []string
+
+		tbl.ForEach(func(key, _ lua.LValue) {
+			if str, ok := key.(lua.LString); ok {
+				keys = append(keys, string(str))
+			}
+		})
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			result = append(result, tbl.RawGetString(key))
+		}
+	}
+
+	return result, nil
+}
+
+func apiJSONPath(L *lua.LState) int {
+	value := L.CheckAny(1)
+	expr := L.CheckString(2)
+
+	matches, err := QueryJSONPath(value, expr)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	result := L.CreateTable(len(matches), 0)
+	for _, match := range matches {
+		result.Append(match)
+	}
+
+	L.Push(result)
+
+	return 1
+}
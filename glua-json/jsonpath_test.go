@@ -0,0 +1,180 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const jsonPathTestDocument = `{
+	"metadata": {
+		"name": "web",
+		"labels": {"app.kubernetes.io/name": "web", "tier": "frontend"}
+	},
+	"spec": {
+		"replicas": 3,
+		"containers": [
+			{"name": "app", "image": "app:1", "ports": [{"containerPort": 80}, {"containerPort": 443}]},
+			{"name": "proxy", "image": "proxy:1", "ports": [{"containerPort": 8080}]}
+		]
+	},
+	"status": {
+		"conditions": [
+			{"type": "Available", "status": "True", "observedGeneration": 2},
+			{"type": "Ready", "status": "False", "observedGeneration": 3}
+		]
+	}
+}`
+
+func TestJSONPathLua(t *testing.T) {
+	str := `
+	local json = require("json")
+	assert(type(json.jsonpath) == "function")
+
+	local obj = json.decode([[` + jsonPathTestDocument + `]])
+
+	local result = json.jsonpath(obj, '{.status.conditions[?(@.type=="Ready")].status}')
+	assert(#result == 1)
+	assert(result[1] == "False")
+
+	local images = json.jsonpath(obj, "{.spec.containers[*].image}")
+	assert(#images == 2)
+	assert(images[1] == "app:1")
+	assert(images[2] == "proxy:1")
+
+	local empty = json.jsonpath(obj, "{.status.missing}")
+	assert(type(empty) == "table" and #empty == 0)
+
+	local _, err = json.jsonpath(obj, "{.spec.containers[}")
+	assert(string.find(err, "invalid JSONPath"), err)
+
+	-- Test recursive descent over a cyclic table
+	local cyclic = {x = 1}
+	cyclic.self = cyclic
+	local result = json.jsonpath(cyclic, "{..x}")
+	assert(#result == 1)
+	assert(result[1] == 1)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueryJSONPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected []lua.LValue
+		wantErr  string
+	}{
+		{
+			name:     "field",
+			expr:     "{.metadata.name}",
+			expected: []lua.LValue{lua.LString("web")},
+		},
+		{
+			name:     "without braces and with root",
+			expr:     "$.spec.replicas",
+			expected: []lua.LValue{lua.LNumber(3)},
+		},
+		{
+			name:     "escaped dots",
+			expr:     `{.metadata.labels.app\.kubernetes\.io/name}`,
+			expected: []lua.LValue{lua.LString("web")},
+		},
+		{
+			name:     "bracket notation",
+			expr:     `{.metadata.labels['app.kubernetes.io/name','tier']}`,
+			expected: []lua.LValue{lua.LString("web"), lua.LString("frontend")},
+		},
+		{
+			name:     "index",
+			expr:     "{.spec.containers[1].name}",
+			expected: []lua.LValue{lua.LString("proxy")},
+		},
+		{
+			name:     "negative index",
+			expr:     "{.spec.containers[-1].name}",
+			expected: []lua.LValue{lua.LString("proxy")},
+		},
+		{
+			name:     "slice",
+			expr:     "{.spec.containers[0:1].name}",
+			expected: []lua.LValue{lua.LString("app")},
+		},
+		{
+			name:     "wildcard over object",
+			expr:     "{.metadata.labels.*}",
+			expected: []lua.LValue{lua.LString("web"), lua.LString("frontend")},
+		},
+		{
+			name:     "recursive descent",
+			expr:     "{..containerPort}",
+			expected: []lua.LValue{lua.LNumber(80), lua.LNumber(443), lua.LNumber(8080)},
+		},
+		{
+			name:     "numeric filter",
+			expr:     "{.status.conditions[?(@.observedGeneration >= 3)].type}",
+			expected: []lua.LValue{lua.LString("Ready")},
+		},
+		{
+			name:     "combined filter",
+			expr:     `{.status.conditions[?(@.type=="Ready" || @.status=="True")].type}`,
+			expected: []lua.LValue{lua.LString("Available"), lua.LString("Ready")},
+		},
+		{
+			name:     "existence filter",
+			expr:     "{.spec.containers[?(@.ports[1])].name}",
+			expected: []lua.LValue{lua.LString("app")},
+		},
+		{
+			name:     "several expressions",
+			expr:     `{.metadata.name}{"\n"}{.spec.replicas}`,
+			expected: []lua.LValue{lua.LString("web"), lua.LString("\n"), lua.LNumber(3)},
+		},
+		{
+			name:    "index out of bounds",
+			expr:    "{.spec.containers[5]}",
+			wantErr: "array index out of bounds",
+		},
+		{
+			name:    "range",
+			expr:    "{range .spec.containers[*]}{.name}{end}",
+			wantErr: "range is not supported",
+		},
+		{
+			name:    "unterminated filter",
+			expr:    "{.status.conditions[?(@.type==]}",
+			wantErr: "invalid JSONPath",
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	value, err := luajson.Decode(L, []byte(jsonPathTestDocument))
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := luajson.QueryJSONPath(value, tt.expr)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
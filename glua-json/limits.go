@@ -0,0 +1,151 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ErrLimitExceeded is returned when the input of a decoding function exceeds
+// its Limits.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limits bounds the input decoding functions accept. Zero fields are not
+// limited.
+type Limits struct {
+	// MaxBytes is the largest input size in bytes.
+	MaxBytes int
+	// MaxDepth is the deepest nesting of arrays and objects.
+	MaxDepth int
+	// MaxElements is the largest total number of array elements and object
+	// members.
+	MaxElements int
+}
+
+// DefaultLimits are limits suited to untrusted input, for use with
+// PreloadWithLimits.
+var DefaultLimits = Limits{
+	MaxBytes:    16 << 20,
+	MaxDepth:    256,
+	MaxElements: 1 << 20,
+}
+
+// tighten returns l with each field lowered to the one of other, where other
+// sets a lower limit.
+func (l Limits) tighten(other Limits) Limits {
+	lower := func(current, limit int) int {
+		if limit > 0 && (current == 0 || limit < current) {
+			return limit
+		}
+
+		return current
+	}
+
+	return Limits{
+		MaxBytes:    lower(l.MaxBytes, other.MaxBytes),
+		MaxDepth:    lower(l.MaxDepth, other.MaxDepth),
+		MaxElements: lower(l.MaxElements, other.MaxElements),
+	}
+}
+
+func (l Limits) checkSize(size int) error {
+	if l.MaxBytes > 0 && size > l.MaxBytes {
+		return fmt.Errorf("%w: input of %d bytes exceeds %d bytes", ErrLimitExceeded, size, l.MaxBytes)
+	}
+
+	return nil
+}
+
+// check returns an error if the JSON encoded data exceeds l. It only scans the
+// structure of data, which is not validated.
+func (l Limits) check(data []byte) error {
+	err := l.checkSize(len(data))
+	if err != nil {
+		return err
+	}
+
+	if l.MaxDepth == 0 && l.MaxElements == 0 {
+		return nil
+	}
+
+	depth, elements := 0, 0
+	inString, escaped, first := false, false, false
+
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+
+		// The first element of a container starts at its first character
+		// other than whitespace and the closing bracket.
+		if first && c != ']' && c != '}' {
+			elements++
+		}
+
+		first = false
+
+		switch c {
+		case '"':
+			inString = true
+		case '[', '{':
+			depth++
+			first = true
+
+			if l.MaxDepth > 0 && depth > l.MaxDepth {
+				return fmt.Errorf("%w: nesting depth exceeds %d", ErrLimitExceeded, l.MaxDepth)
+			}
+		case ']', '}':
+			depth--
+		case ',':
+			elements++
+		}
+
+		if l.MaxElements > 0 && elements > l.MaxElements {
+			return fmt.Errorf("%w: element count exceeds %d", ErrLimitExceeded, l.MaxElements)
+		}
+	}
+
+	return nil
+}
+
+// checkLimits returns the limits of the module the running function belongs
+// to, lowered by the maxBytes, maxDepth and maxElements fields of the decode
+// options table tbl, which may be nil.
+func checkLimits(L *lua.LState, tbl *lua.LTable) Limits {
+	var limits Limits
+
+	if ud, ok := L.Get(lua.UpvalueIndex(1)).(*lua.LUserData); ok {
+		limits, _ = ud.Value.(Limits)
+	}
+
+	if tbl == nil {
+		return limits
+	}
+
+	field := func(name string) int {
+		value, _ := tbl.RawGetString(name).(lua.LNumber)
+
+		return int(value)
+	}
+
+	return limits.tighten(Limits{
+		MaxBytes:    field("maxBytes"),
+		MaxDepth:    field("maxDepth"),
+		MaxElements: field("maxElements"),
+	})
+}
@@ -0,0 +1,137 @@
+package json_test
+
+import (
+	"strings"
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestLimitsLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+
+	-- Test the preloaded limits
+	assert(json.decode('{"a":[1,2,3]}').a[3] == 3)
+
+	local value, err = json.decode(string.rep("[", 5) .. string.rep("]", 5))
+	assert(value == nil)
+	assert(err == "limit exceeded: nesting depth exceeds 4", err)
+
+	local value, err = json.decode("[" .. string.rep("1,", 10) .. "1]")
+	assert(value == nil)
+	assert(err == "limit exceeded: element count exceeds 10", err)
+
+	local value, err = json.decode('"' .. string.rep("x", 100) .. '"')
+	assert(value == nil)
+	assert(err == "limit exceeded: input of 102 bytes exceeds 64 bytes", err)
+
+	local value, err = json.fromYAML("a:\n  b:\n    c:\n      d:\n        e: 1\n")
+	assert(value == nil)
+	assert(err == "limit exceeded: nesting depth exceeds 4", err)
+
+	local value, err = json.fromYAMLAll("a: 1\n---\n" .. string.rep("x", 64))
+	assert(value == nil)
+	assert(string.find(err, "^limit exceeded: input of"), err)
+
+	local value, err = json.yaml.parseDocument(string.rep("x", 65))
+	assert(value == nil)
+	assert(string.find(err, "^limit exceeded: input of"), err)
+
+	-- Test options lower the limits but do not raise them
+	local value, err = json.decode("[[1]]", {maxDepth = 1})
+	assert(value == nil)
+	assert(err == "limit exceeded: nesting depth exceeds 1", err)
+
+	local value, err = json.decode(string.rep("[", 5) .. string.rep("]", 5), {maxDepth = 100})
+	assert(value == nil)
+	assert(err == "limit exceeded: nesting depth exceeds 4", err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.PreloadWithLimits(s, luajson.Limits{MaxBytes: 64, MaxDepth: 4, MaxElements: 10})
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		limits  luajson.Limits
+		wantErr string
+	}{
+		{
+			name:   "within limits",
+			input:  `{"a":[1,{"b":2}],"c":"[[[[,,,,"}`,
+			limits: luajson.Limits{MaxBytes: 64, MaxDepth: 3, MaxElements: 5},
+		},
+		{
+			name:   "escaped quotes in strings",
+			input:  `["\"[[[[\\",1]`,
+			limits: luajson.Limits{MaxDepth: 1, MaxElements: 2},
+		},
+		{
+			name:   "empty containers",
+			input:  `[[], {}, [ ]]`,
+			limits: luajson.Limits{MaxElements: 3},
+		},
+		{
+			name:    "too many members",
+			input:   `{"a":1,"b":{"c":2,"d":3}}`,
+			limits:  luajson.Limits{MaxElements: 3},
+			wantErr: "element count exceeds 3",
+		},
+		{
+			name:    "too deep",
+			input:   strings.Repeat(`{"a":`, 11) + "1" + strings.Repeat("}", 11),
+			limits:  luajson.Limits{MaxDepth: 10},
+			wantErr: "nesting depth exceeds 10",
+		},
+		{
+			name:    "too large",
+			input:   `[1,2,3]`,
+			limits:  luajson.Limits{MaxBytes: 6},
+			wantErr: "input of 7 bytes exceeds 6 bytes",
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, preserveOrder := range []bool{false, true} {
+				opts := luajson.DecodeOptions{Limits: tt.limits, PreserveOrder: preserveOrder}
+
+				_, err := luajson.DecodeWithOptions(L, []byte(tt.input), opts)
+				if tt.wantErr != "" {
+					require.ErrorIs(t, err, luajson.ErrLimitExceeded)
+					assert.Contains(t, err.Error(), tt.wantErr)
+
+					continue
+				}
+
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	luajson.PreloadWithLimits(L, luajson.DefaultLimits)
+
+	require.NoError(t, L.DoString(`
+	local json = require("json")
+	assert(json.decode('{"a":[1,2,3]}').a[3] == 3)
+	assert(json.decode(string.rep("[", 300) .. string.rep("]", 300)) == nil)`))
+}
@@ -0,0 +1,206 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// lineError reports the failing line of a JSON Lines stream.
+type lineError struct {
+	line int
+	err  error
+}
+
+func (l *lineError) Error() string {
+	return fmt.Sprintf("line %d: %v", l.line, l.err)
+}
+
+func (l *lineError) Unwrap() error {
+	return l.err
+}
+
+// lineScanner returns the non-blank lines of a JSON Lines stream one by one.
+type lineScanner struct {
+	data []byte
+	line int
+}
+
+// next returns the next non-blank line and its one-based number, or false at
+// the end of the stream.
+func (s *lineScanner) next() ([]byte, int, bool) {
+	for len(s.data) > 0 {
+		s.line++
+
+		line, rest, _ := bytes.Cut(s.data, []byte("\n"))
+		s.data = rest
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, s.line, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+// decodeLine decodes one line of a JSON Lines stream. null is returned as
+// json.null, so that it can be told apart from the end of the stream.
+func decodeLine(L *lua.LState, line []byte, opts DecodeOptions) (lua.LValue, error) {
+	value, err := DecodeWithOptions(L, line, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == lua.LNil {
+		return Null(L), nil
+	}
+
+	return value, nil
+}
+
+// DecodeLines converts each line of the JSON Lines (NDJSON) stream in data to
+// a Lua value according to opts. Blank lines are skipped and null lines are
+// returned as json.null. Errors carry the one-based number of the failing
+// line.
+func DecodeLines(L *lua.LState, data []byte, opts DecodeOptions) ([]lua.LValue, error) {
+	err := opts.Limits.checkSize(len(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// The size limit applies to the stream, the others to each line.
+	opts.Limits.MaxBytes = 0
+
+	scanner := &lineScanner{data: data}
+
+	var values []lua.LValue
+
+	for {
+		line, number, ok := scanner.next()
+		if !ok {
+			return values, nil
+		}
+
+		value, err := decodeLine(L, line, opts)
+		if err != nil {
+			return nil, &lineError{line: number, err: err}
+		}
+
+		values = append(values, value)
+	}
+}
+
+// EncodeLines returns the JSON Lines encoding of values: the compact JSON
+// encoding of each value followed by a newline. Errors carry the one-based
+// index of the failing value.
+func EncodeLines(values []lua.LValue) ([]byte, error) {
+	return EncodeLinesWithOptions(values, EncodeOptions{EscapeHTML: true, SortKeys: true})
+}
+
+// EncodeLinesWithOptions is like EncodeLines, encoding each value according to
+// opts. Prefix and Indent are ignored, as each value must fit on one line.
+func EncodeLinesWithOptions(values []lua.LValue, opts EncodeOptions) ([]byte, error) {
+	opts.Prefix = ""
+	opts.Indent = ""
+
+	var buf bytes.Buffer
+
+	for i, value := range values {
+		data, err := EncodeWithOptions(value, opts)
+		if err != nil {
+			return nil, &lineError{line: i + 1, err: err}
+		}
+
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+func apiDecodeLines(L *lua.LState) int {
+	str := L.CheckString(1)
+	opts := checkDecodeOptions(L, 2)
+
+	values, err := DecodeLines(L, []byte(str), opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	list := L.CreateTable(len(values), 0)
+	for _, value := range values {
+		list.Append(value)
+	}
+
+	if opts.MarkContainers {
+		MarkArray(L, list)
+	}
+
+	L.Push(list)
+
+	return 1
+}
+
+// apiLines returns an iterator decoding one line of the stream per call and
+// returning the value and its line number. Unlike decodeLines it raises an
+// error on an invalid line, as a for loop has no way to return one.
+func apiLines(L *lua.LState) int {
+	str := L.CheckString(1)
+	opts := checkDecodeOptions(L, 2)
+
+	err := opts.Limits.checkSize(len(str))
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+
+	opts.Limits.MaxBytes = 0
+
+	scanner := &lineScanner{data: []byte(str)}
+
+	L.Push(L.NewFunction(func(L *lua.LState) int {
+		line, number, ok := scanner.next()
+		if !ok {
+			L.Push(lua.LNil)
+
+			return 1
+		}
+
+		value, err := decodeLine(L, line, opts)
+		if err != nil {
+			L.RaiseError("%s", (&lineError{line: number, err: err}).Error())
+		}
+
+		L.Push(value)
+		L.Push(lua.LNumber(number))
+
+		return 2
+	}))
+
+	return 1
+}
+
+func apiEncodeLines(L *lua.LState) int {
+	list := L.CheckTable(1)
+	opts := checkEncodeOptions(L, 2)
+
+	values := make([]lua.LValue, 0, list.Len())
+	for i := 1; i <= list.Len(); i++ {
+		values = append(values, list.RawGetInt(i))
+	}
+
+	data, err := EncodeLinesWithOptions(values, opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LString(string(data)))
+
+	return 1
+}
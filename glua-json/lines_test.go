@@ -0,0 +1,97 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestLinesLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.decodeLines) == "function")
+	assert(type(json.lines) == "function")
+	assert(type(json.encodeLines) == "function")
+
+	local dump = '{"verb":"get","code":200}\n' ..
+		'\n' ..
+		'{"verb":"delete","code":403}\r\n' ..
+		'null\n' ..
+		'{"verb":"list","code":200}'
+
+	-- Test decoding a list
+	local events = json.decodeLines(dump)
+	assert(#events == 4)
+	assert(events[2].verb == "delete")
+	assert(events[3] == json.null)
+	assert(events[4].code == 200)
+
+	-- Test the iterator
+	local verbs, numbers = {}, {}
+	for event, line in json.lines(dump) do
+		if event ~= json.null then
+			verbs[#verbs + 1] = event.verb
+			numbers[#numbers + 1] = line
+		end
+	end
+	assert(table.concat(verbs, ",") == "get,delete,list")
+	assert(table.concat(numbers, ",") == "1,3,5")
+
+	-- Test the round trip
+	local out = json.encodeLines(events)
+	assert(out == '{"code":200,"verb":"get"}\n{"code":403,"verb":"delete"}\nnull\n{"code":200,"verb":"list"}\n', out)
+	assert(json.encodeLines({}) == "")
+	assert(json.encodeLines({{a = {1, 2}}}, {indent = "  "}) == '{"a":[1,2]}\n')
+
+	-- Test options
+	local events = json.decodeLines('{"n":1}\n{"n":12345678901234567891}\n', {useNumber = true})
+	assert(json.encode(events[2]) == '{"n":12345678901234567891}')
+
+	-- Test errors
+	local events, err = json.decodeLines('{"a":1}\n\n{"a":\n')
+	assert(events == nil)
+	assert(string.find(err, "^line 3: "), err)
+
+	local ok, err = pcall(function()
+		for event in json.lines('{"a":1}\n{oops}\n') do
+		end
+	end)
+	assert(not ok)
+	assert(string.find(err, "line 2: "), err)
+
+	local out, err = json.encodeLines({{a = 1}, {b = function() end}})
+	assert(out == nil)
+	assert(string.find(err, "^line 2: "), err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeLines(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	values, err := luajson.DecodeLines(L, []byte("{\"a\":1}\n[1,2]\n\"x\"\n"), luajson.DecodeOptions{})
+	require.NoError(t, err)
+	require.Len(t, values, 3)
+
+	data, err := luajson.EncodeLines(values)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n[1,2]\n\"x\"\n", string(data))
+
+	_, err = luajson.DecodeLines(L, []byte("[1]\n[[[1]]]\n"), luajson.DecodeOptions{Limits: luajson.Limits{MaxDepth: 2}})
+	require.ErrorIs(t, err, luajson.ErrLimitExceeded)
+	assert.Contains(t, err.Error(), "line 2: ")
+
+	_, err = luajson.DecodeLines(L, []byte("[1]\n[2]\n"), luajson.DecodeOptions{Limits: luajson.Limits{MaxBytes: 4}})
+	require.ErrorIs(t, err, luajson.ErrLimitExceeded)
+}
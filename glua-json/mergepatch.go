@@ -0,0 +1,302 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// patchDirective is the key strategic merge patches use to carry directives.
+const patchDirective = "$patch"
+
+// DefaultMergeKeys maps the names of well-known Kubernetes list fields to the
+// field that identifies their items in a strategic merge patch.
+var DefaultMergeKeys = map[string]string{
+	"containers":                "name",
+	"env":                       "name",
+	"ephemeralContainers":       "name",
+	"hostAliases":               "ip",
+	"imagePullSecrets":          "name",
+	"initContainers":            "name",
+	"ports":                     "containerPort",
+	"tolerations":               "key",
+	"topologySpreadConstraints": "topologyKey",
+	"volumeDevices":             "devicePath",
+	"volumeMounts":              "mountPath",
+	"volumes":                   "name",
+}
+
+var errInvalidDirective = errors.New("invalid $patch directive")
+
+// MergePatch applies the RFC 7386 JSON Merge Patch patch to target and returns
+// the merged document. Null values in patch, that is the Null sentinel, delete
+// the corresponding keys, so patches decoded from JSON or YAML need the
+// PreserveNull option to delete anything. Unmarked empty tables in patch are
+// empty objects, which change nothing; use MarkArray to set an empty array.
+// target is left untouched.
+func MergePatch(L *lua.LState, target, patch lua.LValue) (lua.LValue, error) {
+	goTarget, err := FromLua(target)
+	if err != nil {
+		return nil, err
+	}
+
+	goPatch, err := patchFromLua(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToLuaWithOptions(L, mergePatch(goTarget, goPatch), losslessDecodeOptions)
+}
+
+// StrategicMergePatch applies patch to target like MergePatch, except that
+// lists whose field name appears in mergeKeys are merged item by item, using
+// the mapped field to match items, instead of being replaced. A nil mergeKeys
+// uses DefaultMergeKeys.
+//
+// Objects in patch may carry a "$patch" directive: "replace" replaces the
+// target object instead of merging into it, and "delete" removes it, which for
+// list items removes the item with the same merge key.
+func StrategicMergePatch(L *lua.LState, target, patch lua.LValue, mergeKeys map[string]string) (lua.LValue, error) {
+	if mergeKeys == nil {
+		mergeKeys = DefaultMergeKeys
+	}
+
+	goTarget, err := FromLua(target)
+	if err != nil {
+		return nil, err
+	}
+
+	goPatch, err := patchFromLua(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, deleted, err := strategicMerge(goTarget, goPatch, mergeKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted {
+		return lua.LNil, nil
+	}
+
+	return ToLuaWithOptions(L, merged, losslessDecodeOptions)
+}
+
+// patchFromLua converts patch like FromLua, except that unmarked empty tables
+// outside of arrays become empty objects. Lua cannot tell {} from [], and an
+// empty object leaves the target untouched where an empty array would
+// replace it.
+func patchFromLua(patch lua.LValue) (any, error) {
+	state := &encodeState{
+		opts:         EncodeOptions{SortKeys: true},
+		visited:      make(map[*lua.LTable]bool),
+		emptyObjects: true,
+	}
+
+	result, err := jsonValue{patch, state}.toGo()
+	if err != nil {
+		return nil, withPathSegment(err, "")
+	}
+
+	return result, nil
+}
+
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any, len(patchObj))
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+
+			continue
+		}
+
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
+
+// strategicMerge merges patch into target. deleted reports whether patch asks
+// for the target to be removed.
+func strategicMerge(target, patch any, mergeKeys map[string]string) (merged any, deleted bool, err error) {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch, false, nil
+	}
+
+	switch directive := patchObj[patchDirective]; directive {
+	case nil:
+	case "delete":
+		return nil, true, nil
+	case "replace":
+		replaced := make(map[string]any, len(patchObj))
+		for key, value := range patchObj {
+			if key != patchDirective && value != nil {
+				replaced[key] = value
+			}
+		}
+
+		return replaced, false, nil
+	default:
+		return nil, false, fmt.Errorf("%w %v", errInvalidDirective, directive)
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any, len(patchObj))
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+
+			continue
+		}
+
+		mergeKey, hasMergeKey := mergeKeys[key]
+		targetList, isTargetList := targetObj[key].([]any)
+		patchList, isPatchList := value.([]any)
+
+		if hasMergeKey && isTargetList && isPatchList {
+			targetObj[key], err = mergeList(targetList, patchList, mergeKey, mergeKeys)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s: %w", key, err)
+			}
+
+			continue
+		}
+
+		merged, deleted, err := strategicMerge(targetObj[key], value, mergeKeys)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", key, err)
+		}
+
+		if deleted {
+			delete(targetObj, key)
+
+			continue
+		}
+
+		targetObj[key] = merged
+	}
+
+	return targetObj, false, nil
+}
+
+// mergeList merges the items of patch into target, matching items on the
+// mergeKey field. Lists whose patch items are not objects carrying mergeKey are
+// replaced as a whole.
+func mergeList(target, patch []any, mergeKey string, mergeKeys map[string]string) ([]any, error) {
+	for _, item := range patch {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return patch, nil
+		}
+
+		if _, ok := obj[mergeKey]; !ok {
+			return patch, nil
+		}
+	}
+
+	result := make([]any, len(target))
+	copy(result, target)
+
+	for _, item := range patch {
+		patchItem, _ := item.(map[string]any)
+
+		index := -1
+
+		for i, targetItem := range result {
+			obj, ok := targetItem.(map[string]any)
+			if ok && reflect.DeepEqual(obj[mergeKey], patchItem[mergeKey]) {
+				index = i
+
+				break
+			}
+		}
+
+		if index < 0 {
+			merged, deleted, err := strategicMerge(nil, patchItem, mergeKeys)
+			if err != nil {
+				return nil, err
+			}
+
+			if !deleted {
+				result = append(result, merged)
+			}
+
+			continue
+		}
+
+		merged, deleted, err := strategicMerge(result[index], patchItem, mergeKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		if deleted {
+			result = append(result[:index], result[index+1:]...)
+
+			continue
+		}
+
+		result[index] = merged
+	}
+
+	return result, nil
+}
+
+func apiMergePatch(L *lua.LState) int {
+	target := L.CheckAny(1)
+	patch := L.CheckAny(2)
+
+	value, err := MergePatch(L, target, patch)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(value)
+
+	return 1
+}
+
+func apiStrategicMergePatch(L *lua.LState) int {
+	target := L.CheckAny(1)
+	patch := L.CheckAny(2)
+
+	var mergeKeys map[string]string
+
+	if tbl := L.OptTable(3, nil); tbl != nil {
+		mergeKeys = make(map[string]string)
+
+		tbl.ForEach(func(key, value lua.LValue) {
+			mergeKeys[key.String()] = value.String()
+		})
+	}
+
+	value, err := StrategicMergePatch(L, target, patch, mergeKeys)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(value)
+
+	return 1
+}
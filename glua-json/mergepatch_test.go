@@ -0,0 +1,234 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestMergePatchLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.mergePatch) == "function")
+	assert(type(json.strategicMergePatch) == "function")
+
+	local live = json.fromYAML([[
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  containers:
+  - name: app
+    image: app:1
+  - name: proxy
+    image: proxy:1
+]])
+
+	-- Test RFC 7386 merge: lists are replaced
+	local patch = json.fromYAML([[
+metadata:
+  labels:
+    tier: frontend
+spec:
+  containers:
+  - name: app
+    image: app:2
+]])
+	local merged = json.mergePatch(live, patch)
+	assert(merged.metadata.name == "web")
+	assert(merged.metadata.labels.app == "web")
+	assert(merged.metadata.labels.tier == "frontend")
+	assert(#merged.spec.containers == 1)
+	assert(merged.spec.containers[1].image == "app:2")
+
+	-- Test strategic merge: containers are merged by name
+	local merged = json.strategicMergePatch(live, patch)
+	assert(#merged.spec.containers == 2)
+	assert(merged.spec.containers[1].image == "app:2")
+	assert(merged.spec.containers[2].image == "proxy:1")
+
+	-- Test $patch directives
+	local merged = json.strategicMergePatch(live, json.fromYAML([[
+metadata:
+  labels:
+    $patch: replace
+    owner: team-a
+spec:
+  containers:
+  - name: proxy
+    $patch: delete
+  - name: sidecar
+    image: sidecar:1
+]]))
+	assert(merged.metadata.labels.app == nil)
+	assert(merged.metadata.labels.owner == "team-a")
+	assert(merged.metadata.labels["$patch"] == nil)
+	assert(#merged.spec.containers == 2)
+	assert(merged.spec.containers[1].name == "app")
+	assert(merged.spec.containers[2].name == "sidecar")
+
+	-- Test custom merge keys
+	local merged = json.strategicMergePatch(
+		{rules = {{host = "a", port = 80}}},
+		{rules = {{host = "a", port = 8080}, {host = "b", port = 80}}},
+		{rules = "host"})
+	assert(#merged.rules == 2)
+	assert(merged.rules[1].port == 8080)
+
+	-- Test empty tables in patches
+	local target = {a = 1, b = {c = 2}}
+	assert(json.encode(json.mergePatch(target, {})) == '{"a":1,"b":{"c":2}}')
+	assert(json.mergePatch(target, {b = {}}).b.c == 2)
+	assert(json.mergePatch(target, json.fromYAML("b:\n  c: null\n")).b.c == 2)
+	assert(json.strategicMergePatch(target, {b = {}}).b.c == 2)
+	assert(json.encode(json.mergePatch(target, {b = json.array()})) == '{"a":1,"b":[]}')
+
+	-- Test deleting keys through a YAML patch
+	local patch = json.fromYAML("a: null\nb:\n  c: null\n", {preserveNull = true})
+	assert(json.encode(json.mergePatch(target, patch)) == '{"b":{}}')
+	assert(json.encode(json.strategicMergePatch(target, patch)) == '{"b":{}}')
+
+	local _, err = json.strategicMergePatch(live, {metadata = {["$patch"] = "merge"}})
+	assert(string.find(err, "invalid $patch directive", 1, true), err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "merge nested objects",
+			target:   `{"a":{"b":1,"c":2}}`,
+			patch:    `{"a":{"c":3,"d":4}}`,
+			expected: `{"a":{"b":1,"c":3,"d":4}}`,
+		},
+		{
+			name:     "replace arrays",
+			target:   `{"a":[1,2]}`,
+			patch:    `{"a":[3]}`,
+			expected: `{"a":[3]}`,
+		},
+		{
+			name:     "replace scalar with object",
+			target:   `{"a":"b"}`,
+			patch:    `{"a":{"c":1}}`,
+			expected: `{"a":{"c":1}}`,
+		},
+		{
+			name:     "non object patch replaces target",
+			target:   `{"a":1}`,
+			patch:    `[1]`,
+			expected: `[1]`,
+		},
+		{
+			name:     "empty patch",
+			target:   `{"a":1}`,
+			patch:    `{}`,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "empty nested patch",
+			target:   `{"a":{"b":1}}`,
+			patch:    `{"a":{}}`,
+			expected: `{"a":{"b":1}}`,
+		},
+		{
+			name:     "empty list item",
+			target:   `{"a":[1]}`,
+			patch:    `{"a":[{}]}`,
+			expected: `{"a":[[]]}`,
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := luajson.Decode(L, []byte(tt.target))
+			require.NoError(t, err)
+
+			patch, err := luajson.Decode(L, []byte(tt.patch))
+			require.NoError(t, err)
+
+			result, err := luajson.MergePatch(L, target, patch)
+			require.NoError(t, err)
+
+			data, err := luajson.Encode(result)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestStrategicMergePatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		patch     string
+		mergeKeys map[string]string
+		expected  string
+	}{
+		{
+			name:     "merge env by name",
+			target:   `{"env":[{"name":"A","value":"1"},{"name":"B","value":"2"}]}`,
+			patch:    `{"env":[{"name":"B","value":"3"},{"name":"C","value":"4"}]}`,
+			expected: `{"env":[{"name":"A","value":"1"},{"name":"B","value":"3"},{"name":"C","value":"4"}]}`,
+		},
+		{
+			name:     "replace lists without merge key",
+			target:   `{"args":["a","b"]}`,
+			patch:    `{"args":["c"]}`,
+			expected: `{"args":["c"]}`,
+		},
+		{
+			name:     "replace lists whose items lack the merge key",
+			target:   `{"env":[{"name":"A"}]}`,
+			patch:    `{"env":[{"value":"1"}]}`,
+			expected: `{"env":[{"value":"1"}]}`,
+		},
+		{
+			name:      "custom merge keys",
+			target:    `{"env":[{"name":"A","value":"1"}]}`,
+			patch:     `{"env":[{"name":"A","value":"2"}]}`,
+			mergeKeys: map[string]string{"other": "name"},
+			expected:  `{"env":[{"name":"A","value":"2"}]}`,
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := luajson.Decode(L, []byte(tt.target))
+			require.NoError(t, err)
+
+			patch, err := luajson.Decode(L, []byte(tt.patch))
+			require.NoError(t, err)
+
+			result, err := luajson.StrategicMergePatch(L, target, patch, tt.mergeKeys)
+			require.NoError(t, err)
+
+			data, err := luajson.Encode(result)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	lua "github.com/yuin/gopher-lua"
+	yamlv3 "go.yaml.in/yaml/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// yamlSeparator separates the documents of a YAML stream.
+const yamlSeparator = "---\n"
+
+// documentError reports the failing document of a YAML stream.
+type documentError struct {
+	index int
+	err   error
+}
+
+func (d *documentError) Error() string {
+	return fmt.Sprintf("document %d: %v", d.index, d.err)
+}
+
+func (d *documentError) Unwrap() error {
+	return d.err
+}
+
+// FromYAMLAll converts each document of the YAML stream in data to Lua values
+// according to opts. Empty and null documents are skipped. Errors carry the
+// one-based index of the failing document in the stream.
+func FromYAMLAll(L *lua.LState, data []byte, opts DecodeOptions) ([]lua.LValue, error) {
+	err := opts.Limits.checkSize(len(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// As with FromYAMLWithOptions, the size of the converted documents is not
+	// limited.
+	opts.Limits.MaxBytes = 0
+
+	decoder := yamlv3.NewDecoder(bytes.NewReader(data))
+
+	var values []lua.LValue
+
+	for index := 1; ; index++ {
+		var node yamlv3.Node
+
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return values, nil
+		}
+
+		if err != nil {
+			return nil, &documentError{index: index, err: err}
+		}
+
+		value, err := decodeYAMLDocument(L, &node, opts)
+		if err != nil {
+			return nil, &documentError{index: index, err: err}
+		}
+
+		if value != lua.LNil {
+			values = append(values, value)
+		}
+	}
+}
+
+func decodeYAMLDocument(L *lua.LState, node *yamlv3.Node, opts DecodeOptions) (lua.LValue, error) {
+	if len(node.Content) == 0 {
+		return lua.LNil, nil
+	}
+
+	var jsonData []byte
+
+	if opts.PreserveOrder {
+		var buf bytes.Buffer
+
+		err := writeYAMLNodeJSON(&buf, node)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonData = buf.Bytes()
+	} else {
+		data, err := yamlv3.Marshal(node)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonData, err = yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if bytes.Equal(jsonData, []byte("null")) {
+		return lua.LNil, nil
+	}
+
+	return DecodeWithOptions(L, jsonData, opts)
+}
+
+// ToYAMLAll returns the YAML encoding of values as a stream of documents
+// separated by "---". Errors carry the one-based index of the failing value.
+func ToYAMLAll(values []lua.LValue) ([]byte, error) {
+	return toYAMLAll(values, EncodeOptions{EscapeHTML: true, SortKeys: true})
+}
+
+func toYAMLAll(values []lua.LValue, opts EncodeOptions) ([]byte, error) {
+	var buf bytes.Buffer
+
+	for i, value := range values {
+		data, err := toYAML(value, opts)
+		if err != nil {
+			return nil, &documentError{index: i + 1, err: err}
+		}
+
+		if i > 0 {
+			buf.WriteString(yamlSeparator)
+		}
+
+		buf.Write(data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func apiFromYAMLAll(L *lua.LState) int {
+	str := L.CheckString(1)
+	opts := checkDecodeOptions(L, 2)
+
+	values, err := FromYAMLAll(L, []byte(str), opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	list := L.CreateTable(len(values), 0)
+	for _, value := range values {
+		list.Append(value)
+	}
+
+	if opts.MarkContainers {
+		MarkArray(L, list)
+	}
+
+	L.Push(list)
+
+	return 1
+}
+
+func apiToYAMLAll(L *lua.LState) int {
+	list := L.CheckTable(1)
+
+	values := make([]lua.LValue, 0, list.Len())
+	for i := 1; i <= list.Len(); i++ {
+		values = append(values, list.RawGetInt(i))
+	}
+
+	data, err := toYAMLAll(values, EncodeOptions{EscapeHTML: true, SortKeys: true, State: L})
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LString(string(data)))
+
+	return 1
+}
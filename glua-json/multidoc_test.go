@@ -0,0 +1,144 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestYAMLAllLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.fromYAMLAll) == "function")
+	assert(type(json.toYAMLAll) == "function")
+
+	-- Test decoding several documents
+	local manifests = [[
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: apps
+]]
+	local docs = json.fromYAMLAll(manifests)
+	assert(#docs == 2)
+	assert(docs[1].kind == "Namespace")
+	assert(docs[2].metadata.namespace == "apps")
+
+	-- Test the round trip
+	assert(json.toYAMLAll(docs) == manifests, json.toYAMLAll(docs))
+
+	-- Test options
+	local docs = json.fromYAMLAll("b: 1\na: 2\n---\nz: 3\n", {preserveOrder = true})
+	assert(json.encode(docs[1]) == '{"b":1,"a":2}')
+
+	-- Test empty input
+	local docs = json.fromYAMLAll("")
+	assert(#docs == 0)
+	assert(json.toYAMLAll(docs) == "")
+
+	-- Test errors
+	local docs, err = json.fromYAMLAll("a: 1\n---\ninvalid: yaml: content:\n")
+	assert(docs == nil)
+	assert(string.find(err, "^document 2: "), err)
+
+	local out, err = json.toYAMLAll({{a = 1}, {b = function() end}})
+	assert(out == nil)
+	assert(string.find(err, "^document 2: "), err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFromYAMLAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "single document",
+			input:    "a: 1\n",
+			expected: []string{`{"a":1}`},
+		},
+		{
+			name:     "leading and trailing separators",
+			input:    "---\na: 1\n---\nb: 2\n---\n",
+			expected: []string{`{"a":1}`, `{"b":2}`},
+		},
+		{
+			name:     "empty and null documents",
+			input:    "a: 1\n---\n# comment only\n---\nnull\n---\n- x\n",
+			expected: []string{`{"a":1}`, `["x"]`},
+		},
+		{
+			name:     "anchors",
+			input:    "a: &v 1\nb: *v\n---\nc: 2\n",
+			expected: []string{`{"a":1,"b":1}`, `{"c":2}`},
+		},
+		{
+			name:    "invalid document",
+			input:   "a: 1\n---\nb: [\n",
+			wantErr: "document 2: ",
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := luajson.FromYAMLAll(L, []byte(tt.input), luajson.DecodeOptions{})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			actual := make([]string, 0, len(values))
+
+			for _, value := range values {
+				data, err := luajson.Encode(value)
+				require.NoError(t, err)
+
+				actual = append(actual, string(data))
+			}
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestToYAMLAll(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	first := L.NewTable()
+	first.RawSetString("a", lua.LNumber(1))
+
+	data, err := luajson.ToYAMLAll([]lua.LValue{first, lua.LString("text")})
+	require.NoError(t, err)
+	assert.Equal(t, "a: 1\n---\ntext\n", string(data))
+
+	_, err = luajson.ToYAMLAll([]lua.LValue{first, L.NewFunction(nil)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "document 2: ")
+}
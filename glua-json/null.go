@@ -0,0 +1,47 @@
+package json
+
+import (
+	lua "github.com/yuin/gopher-lua"
+)
+
+// nullRegistryKey is the registry field holding the per-state null sentinel.
+const nullRegistryKey = "json.null"
+
+// nullValue is the Go value of the null sentinel userdata.
+type nullValue struct{}
+
+// Null returns the sentinel userdata that stands for an explicit JSON null in
+// L. It is exposed to Lua as json.null. Unlike nil, it can be stored in
+// tables, so that keys holding null survive a decode/encode round trip.
+func Null(L *lua.LState) lua.LValue {
+	if null := L.G.Registry.RawGetString(nullRegistryKey); null != lua.LNil {
+		return null
+	}
+
+	mt := L.NewTable()
+	mt.RawSetString("__tostring", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString("null"))
+
+		return 1
+	}))
+
+	null := L.NewUserData()
+	null.Value = nullValue{}
+	null.Metatable = mt
+
+	L.G.Registry.RawSetString(nullRegistryKey, null)
+
+	return null
+}
+
+// IsNull reports whether value is the null sentinel.
+func IsNull(value lua.LValue) bool {
+	ud, ok := value.(*lua.LUserData)
+	if !ok {
+		return false
+	}
+
+	_, ok = ud.Value.(nullValue)
+
+	return ok
+}
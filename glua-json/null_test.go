@@ -0,0 +1,86 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestNullLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.null) == "userdata")
+	assert(tostring(json.null) == "null")
+	assert(json.encode(json.null) == "null")
+	assert(json.encode({a = json.null}) == '{"a":null}')
+	assert(json.encode({1, json.null, 3}) == "[1,null,3]")
+
+	-- Test nulls are dropped by default
+	assert(json.encode(json.decode('{"a":null}')) == "[]")
+
+	-- Test nulls are preserved on request
+	local obj = json.decode('{"a":null,"b":[null,1]}', {preserveNull = true})
+	assert(obj.a == json.null)
+	assert(obj.b[1] == json.null)
+	assert(json.encode(obj) == '{"a":null,"b":[null,1]}')
+	assert(json.decode("null", {preserveNull = true}) == json.null)
+
+	local obj = json.fromYAML("a: null\nb: ~\n", {preserveNull = true})
+	assert(obj.a == json.null)
+	assert(obj.b == json.null)
+
+	-- Test json.null deletes keys in merge patches
+	local merged = json.mergePatch({a = 1, b = 2}, {a = json.null})
+	assert(merged.a == nil)
+	assert(merged.b == 2)
+
+	local merged = json.mergePatch({a = 1, b = 2}, json.fromYAML("a: null\n", {preserveNull = true}))
+	assert(merged.a == nil)
+	assert(merged.b == 2)
+
+	-- Test json.null survives patches and diffs
+	local ops = json.diff({a = 1}, {a = json.null})
+	assert(ops[1].op == "replace")
+	assert(ops[1].value == json.null)
+
+	local patched = json.patch({a = 1}, ops)
+	assert(patched.a == json.null)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNull(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	null := luajson.Null(L)
+	assert.Same(t, null, luajson.Null(L))
+	assert.True(t, luajson.IsNull(null))
+	assert.False(t, luajson.IsNull(lua.LNil))
+	assert.False(t, luajson.IsNull(L.NewUserData()))
+
+	value, err := luajson.DecodeWithOptions(L, []byte(`{"a":null}`), luajson.DecodeOptions{PreserveNull: true})
+	require.NoError(t, err)
+
+	tbl, ok := value.(*lua.LTable)
+	require.True(t, ok)
+	assert.Equal(t, null, tbl.RawGetString("a"))
+
+	data, err := luajson.Encode(value)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":null}`, string(data))
+
+	_, err = luajson.Encode(L.NewUserData())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot encode userdata to JSON")
+}
@@ -0,0 +1,125 @@
+package json
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// numberTypeName is the registry name of the metatable of large integers.
+const numberTypeName = "json.number"
+
+// maxExactInteger is the largest integer a lua.LNumber holds exactly.
+const maxExactInteger = 1 << 53
+
+// decodeNumber converts a JSON number to a Lua value without losing
+// precision: integers up to 2^53 in magnitude and non-integral numbers become
+// lua.LNumber, larger integers become userdata that encode back to the same
+// digits.
+func decodeNumber(L *lua.LState, number json.Number) lua.LValue {
+	str := number.String()
+
+	if !strings.ContainsAny(str, ".eE") {
+		n, err := strconv.ParseInt(str, 10, 64)
+		if err == nil && n >= -maxExactInteger && n <= maxExactInteger {
+			return lua.LNumber(n)
+		}
+
+		ud := L.NewUserData()
+		ud.Value = number
+		ud.Metatable = numberMetatable(L)
+
+		return ud
+	}
+
+	f, err := number.Float64()
+	if err != nil {
+		return lua.LString(str)
+	}
+
+	return lua.LNumber(f)
+}
+
+func numberMetatable(L *lua.LState) *lua.LTable {
+	mt := L.GetTypeMetatable(numberTypeName)
+	if tbl, ok := mt.(*lua.LTable); ok {
+		return tbl
+	}
+
+	tbl := L.NewTypeMetatable(numberTypeName)
+	L.SetFuncs(tbl, map[string]lua.LGFunction{
+		"__tostring": numberToString,
+		"__eq":       numberEq,
+		"__lt":       numberLt,
+		"__le":       numberLe,
+	})
+
+	return tbl
+}
+
+// largeNumber returns the digits of a large integer userdata.
+func largeNumber(value lua.LValue) (json.Number, bool) {
+	ud, ok := value.(*lua.LUserData)
+	if !ok {
+		return "", false
+	}
+
+	number, ok := ud.Value.(json.Number)
+
+	return number, ok
+}
+
+// encodeNumber returns the JSON encoding of number. Integral numbers are
+// written without exponent.
+func encodeNumber(number lua.LNumber) ([]byte, error) {
+	f := float64(number)
+	if f == math.Trunc(f) && !math.IsInf(f, 0) {
+		return []byte(strconv.FormatFloat(f, 'f', -1, 64)), nil
+	}
+
+	return json.Marshal(f)
+}
+
+func compareNumbers(L *lua.LState) int {
+	a, aok := largeNumber(L.CheckUserData(1))
+	b, bok := largeNumber(L.CheckUserData(2))
+
+	if !aok || !bok {
+		L.RaiseError("attempt to compare %s with %s", L.Get(1).Type(), L.Get(2).Type())
+	}
+
+	x, _ := new(big.Int).SetString(a.String(), 10)
+	y, _ := new(big.Int).SetString(b.String(), 10)
+
+	return x.Cmp(y)
+}
+
+func numberToString(L *lua.LState) int {
+	number, _ := largeNumber(L.CheckUserData(1))
+
+	L.Push(lua.LString(number.String()))
+
+	return 1
+}
+
+func numberEq(L *lua.LState) int {
+	L.Push(lua.LBool(compareNumbers(L) == 0))
+
+	return 1
+}
+
+func numberLt(L *lua.LState) int {
+	L.Push(lua.LBool(compareNumbers(L) < 0))
+
+	return 1
+}
+
+func numberLe(L *lua.LState) int {
+	L.Push(lua.LBool(compareNumbers(L) <= 0))
+
+	return 1
+}
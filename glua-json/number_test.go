@@ -0,0 +1,129 @@
+package json_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestNumberLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+
+	-- Test integral numbers are encoded without exponent
+	assert(json.encode(1e21) == "1000000000000000000000")
+	assert(json.encode(2^53) == "9007199254740992")
+	assert(json.encode(-1e22) == "-10000000000000000000000")
+	assert(json.encode(1.5) == "1.5")
+	assert(json.toYAML({bytes = 1e18}) == "bytes: 1000000000000000000\n")
+
+	-- Test large integers keep their digits
+	local obj = json.decode('{"uid":12345678901234567891,"generation":3,"ratio":0.25}', {useNumber = true})
+	assert(type(obj.uid) == "userdata")
+	assert(tostring(obj.uid) == "12345678901234567891")
+	assert(obj.generation == 3)
+	assert(obj.ratio == 0.25)
+	assert(json.encode(obj) == '{"generation":3,"ratio":0.25,"uid":12345678901234567891}')
+
+	-- Test large integers compare with each other
+	local a = json.decode("9007199254740993", {useNumber = true})
+	local b = json.decode("9007199254740993", {useNumber = true})
+	local c = json.decode("9007199254740994", {useNumber = true})
+	assert(a == b)
+	assert(a ~= c)
+	assert(a < c)
+	assert(c >= b)
+
+	-- Test YAML integers
+	local obj = json.fromYAML("observedGeneration: 9007199254740993\n", {useNumber = true})
+	assert(tostring(obj.observedGeneration) == "9007199254740993")
+	assert(json.toYAML(obj) == "observedGeneration: 9007199254740993\n")
+
+	-- Test default decoding is unchanged
+	assert(json.decode("9007199254740993") == 9007199254740992)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeUseNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "small integer",
+			input:    `42`,
+			expected: `42`,
+		},
+		{
+			name:     "largest exact integer",
+			input:    `-9007199254740992`,
+			expected: `-9007199254740992`,
+		},
+		{
+			name:     "int64 beyond 2^53",
+			input:    `[9223372036854775807]`,
+			expected: `[9223372036854775807]`,
+		},
+		{
+			name:     "beyond int64",
+			input:    `{"a":123456789012345678901234567890}`,
+			expected: `{"a":123456789012345678901234567890}`,
+		},
+		{
+			name:     "float",
+			input:    `3.14`,
+			expected: `3.14`,
+		},
+		{
+			name:    "trailing data",
+			input:   `1 2`,
+			wantErr: "invalid data after top-level value",
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := luajson.DecodeWithOptions(L, []byte(tt.input), luajson.DecodeOptions{UseNumber: true})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			data, err := luajson.Encode(value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestDecodeValueUseNumber(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	v := luajson.DecodeValueWithOptions(L, json.Number("124"), luajson.DecodeOptions{UseNumber: true})
+	assert.Equal(t, lua.LNumber(124), v)
+
+	v = luajson.DecodeValueWithOptions(L, json.Number("124.11"), luajson.DecodeOptions{UseNumber: true})
+	assert.Equal(t, lua.LNumber(124.11), v)
+}
@@ -0,0 +1,416 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// yamlMergeTag is the tag of the << key merging mappings into a mapping.
+const yamlMergeTag = "!!merge"
+
+// decodeOrdered converts the JSON encoded data to Lua values like
+// DecodeWithOptions, inserting object keys in the order they appear in data
+// and marking objects with MarkOrdered.
+func decodeOrdered(L *lua.LState, data []byte, opts DecodeOptions) (lua.LValue, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeOrderedValue(L, decoder, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid data after top-level value")
+	}
+
+	return value, nil
+}
+
+func decodeOrderedValue(L *lua.LState, decoder *json.Decoder, opts DecodeOptions) (lua.LValue, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch converted := token.(type) {
+	case json.Delim:
+		if converted == '[' {
+			arr := L.NewTable()
+
+			for decoder.More() {
+				item, err := decodeOrderedValue(L, decoder, opts)
+				if err != nil {
+					return nil, err
+				}
+
+				arr.Append(item)
+			}
+
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+
+			if opts.MarkContainers {
+				MarkArray(L, arr)
+			}
+
+			return arr, nil
+		}
+
+		tbl := L.NewTable()
+
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			key, _ := token.(string)
+
+			item, err := decodeOrderedValue(L, decoder, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			tbl.RawSetString(key, item)
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+
+		MarkOrdered(L, tbl)
+
+		if opts.ObjectHook != nil {
+			return opts.ObjectHook(L, tbl), nil
+		}
+
+		return tbl, nil
+	case json.Number:
+		if opts.NumberHook != nil {
+			return opts.NumberHook(L, converted), nil
+		}
+
+		if opts.UseNumber {
+			return decodeNumber(L, converted), nil
+		}
+
+		f, err := converted.Float64()
+		if err != nil {
+			return nil, err
+		}
+
+		return lua.LNumber(f), nil
+	default:
+		return DecodeValueWithOptions(L, converted, opts), nil
+	}
+}
+
+// yamlToOrderedJSON converts YAML to JSON like yaml.YAMLToJSON, keeping the
+// order of mapping keys. Plain scalars resolve to the same types, including
+// the YAML 1.1 booleans such as yes and off.
+func yamlToOrderedJSON(data []byte) ([]byte, error) {
+	var node yamlv3.Node
+
+	err := yamlv3.Unmarshal(data, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = writeYAMLNodeJSON(&buf, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeYAMLNodeJSON(buf *bytes.Buffer, node *yamlv3.Node) error {
+	switch node.Kind {
+	case yamlv3.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+
+			return nil
+		}
+
+		return writeYAMLNodeJSON(buf, node.Content[0])
+	case yamlv3.AliasNode:
+		return writeYAMLNodeJSON(buf, node.Alias)
+	case yamlv3.SequenceNode:
+		buf.WriteByte('[')
+
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			err := writeYAMLNodeJSON(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+
+		return nil
+	case yamlv3.MappingNode:
+		keys, values := yamlMappingPairs(node)
+
+		buf.WriteByte('{')
+
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			name, err := encodeString(key, false)
+			if err != nil {
+				return err
+			}
+
+			buf.Write(name)
+			buf.WriteByte(':')
+
+			err = writeYAMLNodeJSON(buf, values[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte('}')
+
+		return nil
+	case yamlv3.ScalarNode:
+		return writeYAMLScalarJSON(buf, node)
+	default:
+		return fmt.Errorf("yaml: unsupported node kind %d at line %d", node.Kind, node.Line)
+	}
+}
+
+// yamlMappingPairs returns the keys and values of a mapping in order, with
+// the mappings merged through << keys inlined where they appear. Keys set
+// explicitly take precedence over merged ones.
+func yamlMappingPairs(node *yamlv3.Node) ([]string, []*yamlv3.Node) {
+	explicit := make(map[string]bool)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := resolveYAMLAlias(node.Content[i]); key.Tag != yamlMergeTag {
+			explicit[yamlKey(key)] = true
+		}
+	}
+
+	var keys []string
+
+	var values []*yamlv3.Node
+
+	index := make(map[string]int)
+
+	add := func(key string, value *yamlv3.Node, override bool) {
+		if i, ok := index[key]; ok {
+			if override {
+				values[i] = value
+			}
+
+			return
+		}
+
+		index[key] = len(keys)
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := resolveYAMLAlias(node.Content[i])
+		value := node.Content[i+1]
+
+		if key.Tag != yamlMergeTag {
+			add(yamlKey(key), value, true)
+
+			continue
+		}
+
+		merged := resolveYAMLAlias(value)
+
+		sources := []*yamlv3.Node{merged}
+		if merged.Kind == yamlv3.SequenceNode {
+			sources = merged.Content
+		}
+
+		for _, source := range sources {
+			mergedKeys, mergedValues := yamlMappingPairs(resolveYAMLAlias(source))
+			for j, mergedKey := range mergedKeys {
+				if !explicit[mergedKey] {
+					add(mergedKey, mergedValues[j], false)
+				}
+			}
+		}
+	}
+
+	return keys, values
+}
+
+// yamlKey returns the object key of a mapping key node, which like with
+// sigs.k8s.io/yaml is the JSON text of booleans and numbers.
+func yamlKey(node *yamlv3.Node) string {
+	if b, ok := yamlBools[node.Value]; ok && node.Style == 0 {
+		return strconv.FormatBool(b)
+	}
+
+	switch node.ShortTag() {
+	case "!!int":
+		if n, ok := new(big.Int).SetString(strings.ReplaceAll(node.Value, "_", ""), 0); ok {
+			return n.String()
+		}
+	case "!!float":
+		if f, err := strconv.ParseFloat(strings.ReplaceAll(node.Value, "_", ""), 64); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	}
+
+	return node.Value
+}
+
+func resolveYAMLAlias(node *yamlv3.Node) *yamlv3.Node {
+	for node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+
+	return node
+}
+
+// yamlBools holds the YAML 1.1 booleans that sigs.k8s.io/yaml, and so
+// FromYAML, resolve plain scalars to, while YAML 1.2 only knows true and
+// false.
+var yamlBools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"true": true, "True": true, "TRUE": true,
+	"on": true, "On": true, "ON": true,
+	"n": false, "N": false, "no": false, "No": false, "NO": false,
+	"false": false, "False": false, "FALSE": false,
+	"off": false, "Off": false, "OFF": false,
+}
+
+func writeYAMLScalarJSON(buf *bytes.Buffer, node *yamlv3.Node) error {
+	if b, ok := yamlBools[node.Value]; ok && node.Style == 0 {
+		buf.WriteString(strconv.FormatBool(b))
+
+		return nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+
+		return nil
+	case "!!int":
+		// Written in decimal with big.Int, so that integers of any size keep
+		// their digits, whatever their base, sign or underscores.
+		n, ok := new(big.Int).SetString(strings.ReplaceAll(node.Value, "_", ""), 0)
+		if !ok {
+			return fmt.Errorf("yaml: cannot convert %s at line %d to JSON", node.Value, node.Line)
+		}
+
+		buf.WriteString(n.String())
+
+		return nil
+	case "!!bool", "!!float":
+		var value any
+
+		err := node.Decode(&value)
+		if err != nil {
+			return err
+		}
+
+		if f, ok := value.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return fmt.Errorf("yaml: cannot convert %s at line %d to JSON", node.Value, node.Line)
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(data)
+
+		return nil
+	default:
+		data, err := encodeString(node.Value, false)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(data)
+
+		return nil
+	}
+}
+
+// jsonToYAML converts JSON to YAML like yaml.JSONToYAML, but keeping the
+// order of object keys and the literals of numbers, so that large and precise
+// numbers are not rounded.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yamlv3.Node
+
+	err := yamlv3.Unmarshal(data, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	clearYAMLStyle(&node)
+
+	var buf bytes.Buffer
+
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	encoder.CompactSeqIndent()
+
+	err = encoder.Encode(&node)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// clearYAMLStyle resets the flow and quoting styles JSON input is parsed with,
+// so that the node is rendered in block style and strings are quoted only
+// when needed, which includes the YAML 1.1 booleans.
+func clearYAMLStyle(node *yamlv3.Node) {
+	node.Style = 0
+
+	if yamlNeedsQuotes(node) {
+		node.Style = yamlv3.DoubleQuotedStyle
+	}
+
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
+
+// yamlNeedsQuotes reports whether node is a string that YAML 1.1 parsers, such
+// as sigs.k8s.io/yaml, would read as a boolean if it were not quoted. yaml.v3
+// already quotes the strings YAML 1.2 would read as another type.
+func yamlNeedsQuotes(node *yamlv3.Node) bool {
+	_, ok := yamlBools[node.Value]
+
+	return ok && node.Kind == yamlv3.ScalarNode && node.ShortTag() == "!!str"
+}
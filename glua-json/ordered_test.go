@@ -0,0 +1,212 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestOrderedLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.ordered) == "function")
+
+	-- Test decode/encode keeps the source order
+	local input = '{"zeta":1,"alpha":{"y":true,"x":[{"b":1,"a":2}]},"mid":"m"}'
+	local obj = json.decode(input, {preserveOrder = true})
+	assert(json.encode(obj) == input)
+	assert(json.encode(obj, {sortKeys = true}) == input)
+
+	-- Test added keys go last and removed keys disappear
+	obj.mid = nil
+	obj.beta = 2
+	assert(json.encode(obj) == '{"zeta":1,"alpha":{"y":true,"x":[{"b":1,"a":2}]},"beta":2}')
+
+	-- Test toYAML keeps the order
+	local obj = json.decode('{"name":"web","data":{"z":"1","a":"2"}}', {preserveOrder = true})
+	assert(json.toYAML(obj) == "name: web\ndata:\n  z: \"1\"\n  a: \"2\"\n", json.toYAML(obj))
+
+	-- Test fromYAML keeps the order
+	local values = [[
+image:
+  tag: "1.0"
+  repository: nginx
+replicaCount: 2
+env:
+- name: B
+  value: b
+- name: A
+  value: a
+]]
+	local obj = json.fromYAML(values, {preserveOrder = true})
+	assert(obj.image.repository == "nginx")
+	assert(json.toYAML(obj) == values, json.toYAML(obj))
+
+	-- Test tables built in Lua
+	local obj = json.ordered()
+	obj.kind = "ConfigMap"
+	obj.apiVersion = "v1"
+	assert(json.encode(obj) == '{"kind":"ConfigMap","apiVersion":"v1"}')
+	assert(json.encode(json.ordered()) == "{}")`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodePreserveOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     luajson.DecodeOptions
+		expected string
+	}{
+		{
+			name:     "nested objects",
+			input:    `{"b":{"d":1,"c":2},"a":[3,{"f":4,"e":5}]}`,
+			expected: `{"b":{"d":1,"c":2},"a":[3,{"f":4,"e":5}]}`,
+		},
+		{
+			name:     "scalars",
+			input:    `"x"`,
+			expected: `"x"`,
+		},
+		{
+			name:     "nulls and numbers",
+			input:    `{"b":null,"a":12345678901234567891}`,
+			opts:     luajson.DecodeOptions{PreserveNull: true, UseNumber: true},
+			expected: `{"b":null,"a":12345678901234567891}`,
+		},
+		{
+			name:     "empty containers",
+			input:    `{"b":{},"a":[]}`,
+			opts:     luajson.DecodeOptions{MarkContainers: true},
+			expected: `{"b":{},"a":[]}`,
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.PreserveOrder = true
+
+			value, err := luajson.DecodeWithOptions(L, []byte(tt.input), opts)
+			require.NoError(t, err)
+
+			data, err := luajson.Encode(value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+
+	_, err := luajson.DecodeWithOptions(L, []byte(`{"a":1} x`), luajson.DecodeOptions{PreserveOrder: true})
+	require.Error(t, err)
+}
+
+func TestFromYAMLPreserveOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "scalar types",
+			input:    "s: text\ni: 9007199254740993\nf: 1.5\nb: true\nn: null\nq: \"42\"\n",
+			expected: `{"s":"text","i":9007199254740993,"f":1.5,"b":true,"q":"42"}`,
+		},
+		{
+			name:     "integer literals",
+			input:    "o: 012\np: +5\nh: 0x1F\nu: 1_000\nb: -0b101\n",
+			expected: `{"o":10,"p":5,"h":31,"u":1000,"b":-5}`,
+		},
+		{
+			name:     "YAML 1.1 booleans",
+			input:    "a: yes\nb: Off\nc: \"on\"\nd: !!str y\n",
+			expected: `{"a":true,"b":false,"c":"on","d":"y"}`,
+		},
+		{
+			name:     "scalar keys",
+			input:    "yes: 1\n0x1F: 2\n1e3: 3\n",
+			expected: `{"true":1,"31":2,"1000":3}`,
+		},
+		{
+			name:     "anchors and merge keys",
+			input:    "base: &base\n  b: 1\n  a: 2\nderived:\n  <<: *base\n  a: 3\n  c: *base\n",
+			expected: `{"base":{"b":1,"a":2},"derived":{"b":1,"a":3,"c":{"b":1,"a":2}}}`,
+		},
+		{
+			name:    "infinity",
+			input:   "f: .inf\n",
+			wantErr: "cannot convert .inf",
+		},
+		{
+			name:    "invalid YAML",
+			input:   "invalid: yaml: content:",
+			wantErr: "yaml:",
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := luajson.DecodeOptions{PreserveOrder: true, UseNumber: true}
+
+			value, err := luajson.FromYAMLWithOptions(L, []byte(tt.input), opts)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			data, err := luajson.Encode(value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestToYAMLOrdered(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	tbl := luajson.MarkOrdered(L, L.NewTable())
+	tbl.RawSetString("version", lua.LString("true"))
+	tbl.RawSetString("count", lua.LNumber(3))
+	tbl.RawSetString("d", lua.LString("no"))
+	tbl.RawSetString("on", lua.LString("y"))
+	tbl.RawSetString("empty", luajson.MarkObject(L, L.NewTable()))
+
+	list := L.NewTable()
+	list.Append(lua.LString("a"))
+	tbl.RawSetString("list", list)
+
+	data, err := luajson.ToYAML(tbl)
+	require.NoError(t, err)
+	assert.Equal(t, "version: \"true\"\ncount: 3\nd: \"no\"\n\"on\": \"y\"\nempty: {}\nlist:\n- a\n", string(data))
+
+	for _, preserveOrder := range []bool{false, true} {
+		value, err := luajson.FromYAMLWithOptions(L, data, luajson.DecodeOptions{PreserveOrder: preserveOrder})
+		require.NoError(t, err)
+
+		obj := value.(*lua.LTable)
+		assert.Equal(t, lua.LString("no"), obj.RawGetString("d"))
+		assert.Equal(t, lua.LString("y"), obj.RawGetString("on"))
+	}
+}
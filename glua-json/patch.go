@@ -0,0 +1,515 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var errTestFailed = errors.New("test failed")
+
+// losslessDecodeOptions are used to convert the results of operations on
+// documents back to Lua, keeping explicit nulls and empty objects.
+var losslessDecodeOptions = DecodeOptions{PreserveNull: true, MarkContainers: true, UseNumber: true}
+
+// patchError reports the failing operation of a JSON Patch.
+type patchError struct {
+	index int
+	op    string
+	path  string
+	err   error
+}
+
+func (p *patchError) Error() string {
+	return fmt.Sprintf("patch operation %d (%s %s): %v", p.index, p.op, p.path, p.err)
+}
+
+func (p *patchError) Unwrap() error {
+	return p.err
+}
+
+// Patch applies the RFC 6902 JSON Patch ops to doc and returns the patched
+// document. ops must be a list of operation tables, each with an op and a path
+// field and, depending on the operation, a value or a from field. test
+// compares values as Equal does, except that empty arrays and objects match.
+// doc is left untouched.
+func Patch(L *lua.LState, doc, ops lua.LValue) (lua.LValue, error) {
+	goDoc, err := FromLua(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	goOps, err := FromLua(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := goOps.([]any)
+	if !ok {
+		return nil, errors.New("patch operations must be a list")
+	}
+
+	for i, item := range list {
+		op, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("patch operation %d is not an object", i+1)
+		}
+
+		goDoc, err = applyPatchOp(goDoc, op)
+		if err != nil {
+			name, _ := op["op"].(string)
+			path, _ := op["path"].(string)
+
+			return nil, &patchError{index: i + 1, op: name, path: path, err: err}
+		}
+	}
+
+	return ToLuaWithOptions(L, goDoc, losslessDecodeOptions)
+}
+
+// Diff returns the RFC 6902 JSON Patch that transforms a into b.
+func Diff(L *lua.LState, a, b lua.LValue) (lua.LValue, error) {
+	goA, err := FromLua(a)
+	if err != nil {
+		return nil, err
+	}
+
+	goB, err := FromLua(b)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := diffValues("", goA, goB, []any{})
+
+	return ToLuaWithOptions(L, ops, losslessDecodeOptions)
+}
+
+func applyPatchOp(doc any, op map[string]any) (any, error) {
+	path, ok := op["path"].(string)
+	if !ok {
+		return nil, errors.New("missing path")
+	}
+
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op["op"] {
+	case "add":
+		value, ok := op["value"]
+		if !ok {
+			return nil, errors.New("missing value")
+		}
+
+		return pointerAdd(doc, tokens, value)
+	case "remove":
+		return pointerRemove(doc, tokens)
+	case "replace":
+		value, ok := op["value"]
+		if !ok {
+			return nil, errors.New("missing value")
+		}
+
+		return pointerReplace(doc, tokens, value)
+	case "move":
+		fromTokens, err := patchFrom(op)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(fromTokens) < len(tokens) && isPrefix(fromTokens, tokens) {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+
+		value, err := pointerGet(doc, fromTokens)
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err = pointerRemove(doc, fromTokens)
+		if err != nil {
+			return nil, err
+		}
+
+		return pointerAdd(doc, tokens, value)
+	case "copy":
+		fromTokens, err := patchFrom(op)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := pointerGet(doc, fromTokens)
+		if err != nil {
+			return nil, err
+		}
+
+		return pointerAdd(doc, tokens, deepCopy(value))
+	case "test":
+		expected, ok := op["value"]
+		if !ok {
+			return nil, errors.New("missing value")
+		}
+
+		value, err := pointerGet(doc, tokens)
+		if err != nil {
+			return nil, err
+		}
+
+		if !equalValues(emptyAsObject(value), emptyAsObject(expected)) {
+			return nil, errTestFailed
+		}
+
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %v", op["op"])
+	}
+}
+
+func patchFrom(op map[string]any) ([]string, error) {
+	from, ok := op["from"].(string)
+	if !ok {
+		return nil, errors.New("missing from")
+	}
+
+	return parsePointer(from)
+}
+
+func isPrefix(prefix, tokens []string) bool {
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pointerGet(doc any, tokens []string) (any, error) {
+	current := doc
+
+	for i, token := range tokens {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w at %s", errPathNotFound, formatPointer(tokens[:i+1]))
+			}
+
+			current = value
+		case []any:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%w at %s", errPathNotFound, formatPointer(tokens[:i+1]))
+		}
+	}
+
+	return current, nil
+}
+
+// pointerUpdate walks doc to the parent of the location referenced by tokens
+// and replaces that parent with the result of fn. It returns the updated doc.
+func pointerUpdate(doc any, tokens []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w at /%s", errPathNotFound, escapePointerToken(tokens[0]))
+		}
+
+		updated, err := pointerUpdate(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		node[tokens[0]] = updated
+
+		return node, nil
+	case []any:
+		index, err := arrayIndex(tokens[0], len(node), false)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := pointerUpdate(node[index], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		node[index] = updated
+
+		return node, nil
+	default:
+		return nil, fmt.Errorf("%w at /%s", errPathNotFound, escapePointerToken(tokens[0]))
+	}
+}
+
+func pointerAdd(doc any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return pointerUpdate(doc, tokens, func(parent any, token string) (any, error) {
+		switch node := objectIfEmpty(parent, token).(type) {
+		case map[string]any:
+			node[token] = value
+
+			return node, nil
+		case []any:
+			index, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+
+			return node, nil
+		default:
+			return nil, fmt.Errorf("%w: parent of %q is not a container", errPathNotFound, token)
+		}
+	})
+}
+
+func pointerRemove(doc any, tokens []string) (any, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("cannot remove the document root")
+	}
+
+	return pointerUpdate(doc, tokens, func(parent any, token string) (any, error) {
+		switch node := objectIfEmpty(parent, token).(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("%w: key %q", errPathNotFound, token)
+			}
+
+			delete(node, token)
+
+			return node, nil
+		case []any:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(node[:index], node[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: parent of %q is not a container", errPathNotFound, token)
+		}
+	})
+}
+
+func pointerReplace(doc any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return pointerUpdate(doc, tokens, func(parent any, token string) (any, error) {
+		switch node := objectIfEmpty(parent, token).(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("%w: key %q", errPathNotFound, token)
+			}
+
+			node[token] = value
+
+			return node, nil
+		case []any:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+
+			node[index] = value
+
+			return node, nil
+		default:
+			return nil, fmt.Errorf("%w: parent of %q is not a container", errPathNotFound, token)
+		}
+	})
+}
+
+// objectIfEmpty returns an empty object in place of parent if parent is an
+// empty array and token is not an array index. Unmarked empty Lua tables
+// convert to empty arrays, although they may as well stand for objects.
+func objectIfEmpty(parent any, token string) any {
+	arr, ok := parent.([]any)
+	if !ok || len(arr) > 0 || token == "-" || (token != "" && strings.Trim(token, "0123456789") == "") {
+		return parent
+	}
+
+	return map[string]any{}
+}
+
+func deepCopy(value any) any {
+	switch converted := value.(type) {
+	case map[string]any:
+		obj := make(map[string]any, len(converted))
+		for key, item := range converted {
+			obj[key] = deepCopy(item)
+		}
+
+		return obj
+	case []any:
+		arr := make([]any, len(converted))
+		for i, item := range converted {
+			arr[i] = deepCopy(item)
+		}
+
+		return arr
+	default:
+		return value
+	}
+}
+
+// emptyAsObject returns a copy of value whose empty arrays are empty objects,
+// as an unmarked empty table may stand for either.
+func emptyAsObject(value any) any {
+	switch converted := value.(type) {
+	case map[string]any:
+		obj := make(map[string]any, len(converted))
+		for key, item := range converted {
+			obj[key] = emptyAsObject(item)
+		}
+
+		return obj
+	case []any:
+		if len(converted) == 0 {
+			return map[string]any{}
+		}
+
+		arr := make([]any, len(converted))
+		for i, item := range converted {
+			arr[i] = emptyAsObject(item)
+		}
+
+		return arr
+	default:
+		return value
+	}
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func diffValues(path string, a, b any, ops []any) []any {
+	switch convertedA := a.(type) {
+	case map[string]any:
+		convertedB, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+
+		for _, key := range sortedKeys(convertedA) {
+			childPath := path + "/" + escapePointerToken(key)
+
+			valueB, ok := convertedB[key]
+			if !ok {
+				ops = append(ops, map[string]any{"op": "remove", "path": childPath})
+
+				continue
+			}
+
+			ops = diffValues(childPath, convertedA[key], valueB, ops)
+		}
+
+		for _, key := range sortedKeys(convertedB) {
+			if _, ok := convertedA[key]; !ok {
+				ops = append(ops, map[string]any{
+					"op":    "add",
+					"path":  path + "/" + escapePointerToken(key),
+					"value": convertedB[key],
+				})
+			}
+		}
+
+		return ops
+	case []any:
+		convertedB, ok := b.([]any)
+		if !ok {
+			break
+		}
+
+		common := min(len(convertedA), len(convertedB))
+		for i := range common {
+			ops = diffValues(path+"/"+strconv.Itoa(i), convertedA[i], convertedB[i], ops)
+		}
+
+		for i := common; i < len(convertedB); i++ {
+			ops = append(ops, map[string]any{
+				"op":    "add",
+				"path":  path + "/" + strconv.Itoa(i),
+				"value": convertedB[i],
+			})
+		}
+
+		for i := len(convertedA) - 1; i >= common; i-- {
+			ops = append(ops, map[string]any{"op": "remove", "path": path + "/" + strconv.Itoa(i)})
+		}
+
+		return ops
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		ops = append(ops, map[string]any{"op": "replace", "path": path, "value": b})
+	}
+
+	return ops
+}
+
+func apiPatch(L *lua.LState) int {
+	doc := L.CheckAny(1)
+	ops := L.CheckTable(2)
+
+	value, err := Patch(L, doc, ops)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(value)
+
+	return 1
+}
+
+func apiDiff(L *lua.LState) int {
+	a := L.CheckAny(1)
+	b := L.CheckAny(2)
+
+	ops, err := Diff(L, a, b)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(ops)
+
+	return 1
+}
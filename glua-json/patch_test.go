@@ -0,0 +1,258 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestPatchLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.patch) == "function")
+	assert(type(json.diff) == "function")
+
+	local doc = {metadata = {name = "web"}, spec = {containers = {{name = "a"}, {name = "b"}}}}
+
+	-- Test every operation
+	local patched, err = json.patch(doc, {
+		{op = "add", path = "/metadata/labels", value = {app = "web"}},
+		{op = "replace", path = "/metadata/name", value = "api"},
+		{op = "add", path = "/spec/containers/-", value = {name = "c"}},
+		{op = "remove", path = "/spec/containers/0"},
+		{op = "copy", from = "/metadata/labels", path = "/spec/selector"},
+		{op = "move", from = "/spec/containers/1", path = "/spec/sidecar"},
+		{op = "test", path = "/spec/containers/0/name", value = "b"},
+	})
+	assert(err == nil, err)
+	assert(patched.metadata.name == "api")
+	assert(patched.metadata.labels.app == "web")
+	assert(patched.spec.selector.app == "web")
+	assert(#patched.spec.containers == 1)
+	assert(patched.spec.containers[1].name == "b")
+	assert(patched.spec.sidecar.name == "c")
+
+	-- The input document is left untouched
+	assert(doc.metadata.name == "web")
+	assert(#doc.spec.containers == 2)
+
+	-- Test adding members to empty tables
+	local patched = json.patch(json.decode('{}'), {{op = "add", path = "/a", value = 1}})
+	assert(patched.a == 1)
+
+	-- Test failing operations
+	local _, err = json.patch(doc, {{op = "test", path = "/metadata/name", value = "api"}})
+	assert(string.find(err, "test failed"), err)
+
+	local _, err = json.patch(doc, {{op = "test", path = "/metadata/name"}})
+	assert(string.find(err, "missing value"), err)
+
+	-- Test comparing empty tables
+	assert(json.patch({a = json.object()}, {{op = "test", path = "/a", value = {}}}))
+	assert(json.patch({a = {}}, {{op = "test", path = "/a", value = json.object()}}))
+	assert(json.patch({a = 1}, {{op = "test", path = "/a", value = 1.0}}))
+
+	local _, err = json.patch(doc, {{op = "replace", path = "/status/phase", value = "Ready"}})
+	assert(string.find(err, "path not found"), err)
+
+	-- Test diff produces a patch that can be applied
+	local target = {metadata = {name = "web", labels = {app = "web"}}, spec = {containers = {{name = "a"}}}}
+	local ops = json.diff(doc, target)
+	local result = json.patch(doc, ops)
+	assert(json.encode(result) == json.encode(target))
+	assert(#json.diff(target, target) == 0)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		ops      string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "add object member",
+			doc:      `{"a":1}`,
+			ops:      `[{"op":"add","path":"/b","value":2}]`,
+			expected: `{"a":1,"b":2}`,
+		},
+		{
+			name:     "add array element",
+			doc:      `{"a":[1,3]}`,
+			ops:      `[{"op":"add","path":"/a/1","value":2}]`,
+			expected: `{"a":[1,2,3]}`,
+		},
+		{
+			name:     "add member to empty object",
+			doc:      `{"a":{}}`,
+			ops:      `[{"op":"add","path":"/a/b","value":1}]`,
+			expected: `{"a":{"b":1}}`,
+		},
+		{
+			name:     "add element to empty array",
+			doc:      `{"a":[]}`,
+			ops:      `[{"op":"add","path":"/a/-","value":1}]`,
+			expected: `{"a":[1]}`,
+		},
+		{
+			name:    "replace member of empty object",
+			doc:     `{}`,
+			ops:     `[{"op":"replace","path":"/a","value":1}]`,
+			wantErr: `path not found: key "a"`,
+		},
+		{
+			name:     "replace root",
+			doc:      `{"a":1}`,
+			ops:      `[{"op":"replace","path":"","value":[1]}]`,
+			expected: `[1]`,
+		},
+		{
+			name:     "escaped tokens",
+			doc:      `{"a/b":{"m~n":1}}`,
+			ops:      `[{"op":"replace","path":"/a~1b/m~0n","value":2}]`,
+			expected: `{"a/b":{"m~n":2}}`,
+		},
+		{
+			name:     "test empty object",
+			doc:      `{"a":{"b":{}}}`,
+			ops:      `[{"op":"test","path":"/a","value":{"b":{}}},{"op":"replace","path":"/a","value":1}]`,
+			expected: `{"a":1}`,
+		},
+		{
+			name:    "test without value",
+			doc:     `{"a":1}`,
+			ops:     `[{"op":"test","path":"/a"}]`,
+			wantErr: "missing value",
+		},
+		{
+			name:     "move within array",
+			doc:      `[1,2,3]`,
+			ops:      `[{"op":"move","from":"/0","path":"/2"}]`,
+			expected: `[2,3,1]`,
+		},
+		{
+			name:    "remove missing key",
+			doc:     `{"a":1}`,
+			ops:     `[{"op":"remove","path":"/b"}]`,
+			wantErr: "path not found",
+		},
+		{
+			name:    "index out of range",
+			doc:     `[1]`,
+			ops:     `[{"op":"replace","path":"/5","value":1}]`,
+			wantErr: "invalid array index",
+		},
+		{
+			name:    "invalid pointer",
+			doc:     `{"a":1}`,
+			ops:     `[{"op":"remove","path":"a"}]`,
+			wantErr: "invalid JSON pointer",
+		},
+		{
+			name:    "move into child",
+			doc:     `{"a":{"b":1}}`,
+			ops:     `[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			wantErr: "cannot move",
+		},
+		{
+			name:    "unknown operation",
+			doc:     `{"a":1}`,
+			ops:     `[{"op":"merge","path":"/a"}]`,
+			wantErr: "unknown operation",
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := luajson.Decode(L, []byte(tt.doc))
+			require.NoError(t, err)
+
+			ops, err := luajson.Decode(L, []byte(tt.ops))
+			require.NoError(t, err)
+
+			result, err := luajson.Patch(L, doc, ops)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			data, err := luajson.Encode(result)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			name:     "identical",
+			a:        `{"a":[1,2]}`,
+			b:        `{"a":[1,2]}`,
+			expected: `[]`,
+		},
+		{
+			name:     "object members",
+			a:        `{"a":1,"b":2}`,
+			b:        `{"a":3,"c":4}`,
+			expected: `[{"op":"replace","path":"/a","value":3},{"op":"remove","path":"/b"},{"op":"add","path":"/c","value":4}]`,
+		},
+		{
+			name:     "array shrink",
+			a:        `[1,2,3]`,
+			b:        `[1]`,
+			expected: `[{"op":"remove","path":"/2"},{"op":"remove","path":"/1"}]`,
+		},
+		{
+			name:     "type change",
+			a:        `{"a":{"b":1}}`,
+			b:        `{"a":[1]}`,
+			expected: `[{"op":"replace","path":"/a","value":[1]}]`,
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := luajson.Decode(L, []byte(tt.a))
+			require.NoError(t, err)
+
+			b, err := luajson.Decode(L, []byte(tt.b))
+			require.NoError(t, err)
+
+			ops, err := luajson.Diff(L, a, b)
+			require.NoError(t, err)
+
+			data, err := luajson.Encode(ops)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
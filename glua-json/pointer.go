@@ -0,0 +1,290 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var (
+	errInvalidPointer = errors.New("invalid JSON pointer")
+	errPathNotFound   = errors.New("path not found")
+	errInvalidIndex   = errors.New("invalid array index")
+)
+
+// GetPointer returns the value the RFC 6901 JSON Pointer refers to in value.
+// Array indices in the pointer are zero-based and are translated to Lua's
+// one-based indices.
+func GetPointer(value lua.LValue, pointer string) (lua.LValue, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	return tableGet(value, tokens)
+}
+
+// SetPointer sets the location the RFC 6901 JSON Pointer refers to in value to
+// v. Missing intermediate tables are created; the token "-" appends to an
+// array.
+func SetPointer(L *lua.LState, value lua.LValue, pointer string, v lua.LValue) error {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 {
+		return errors.New("cannot set the document root")
+	}
+
+	current := value
+
+	for i, token := range tokens[:len(tokens)-1] {
+		tbl, ok := current.(*lua.LTable)
+		if !ok {
+			return fmt.Errorf("%s is not a table", formatPointer(tokens[:i]))
+		}
+
+		key := tableKey(tbl, token)
+
+		current = tbl.RawGet(key)
+		if current == lua.LNil {
+			current = L.NewTable()
+			tbl.RawSet(key, current)
+		}
+	}
+
+	tbl, ok := current.(*lua.LTable)
+	if !ok {
+		return fmt.Errorf("%s is not a table", formatPointer(tokens[:len(tokens)-1]))
+	}
+
+	key := tableKey(tbl, tokens[len(tokens)-1])
+	if index, ok := key.(lua.LNumber); ok && int(index) > tbl.Len()+1 {
+		return fmt.Errorf("%w %q: out of range", errInvalidIndex, tokens[len(tokens)-1])
+	}
+
+	tbl.RawSet(key, v)
+
+	return nil
+}
+
+// RemovePointer removes the location the RFC 6901 JSON Pointer refers to from
+// value and returns the removed value. Removing an array element shifts the
+// following elements down.
+func RemovePointer(value lua.LValue, pointer string) (lua.LValue, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New("cannot remove the document root")
+	}
+
+	parent, err := tableGet(value, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, ok := parent.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("%w at %s", errPathNotFound, pointer)
+	}
+
+	key := tableKey(tbl, tokens[len(tokens)-1])
+
+	removed := tbl.RawGet(key)
+	if removed == lua.LNil {
+		return nil, fmt.Errorf("%w at %s", errPathNotFound, pointer)
+	}
+
+	if index, ok := key.(lua.LNumber); ok {
+		tbl.Remove(int(index))
+	} else {
+		tbl.RawSet(key, lua.LNil)
+	}
+
+	return removed, nil
+}
+
+func tableGet(value lua.LValue, tokens []string) (lua.LValue, error) {
+	current := value
+
+	for i, token := range tokens {
+		if p, ok := asProxy(current); ok {
+			var err error
+
+			current, err = p.lookup(p.L, proxyKey(p, token))
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			tbl, ok := current.(*lua.LTable)
+			if !ok {
+				return nil, fmt.Errorf("%w at %s", errPathNotFound, formatPointer(tokens[:i+1]))
+			}
+
+			current = tbl.RawGet(tableKey(tbl, token))
+		}
+
+		if current == lua.LNil {
+			return nil, fmt.Errorf("%w at %s", errPathNotFound, formatPointer(tokens[:i+1]))
+		}
+	}
+
+	return current, nil
+}
+
+// tableKey returns the key token refers to in tbl. Numeric tokens and "-"
+// address array elements, unless tbl is an object.
+func tableKey(tbl *lua.LTable, token string) lua.LValue {
+	if isObjectTable(tbl) {
+		return lua.LString(token)
+	}
+
+	if token == "-" {
+		return lua.LNumber(tbl.Len() + 1)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || strconv.Itoa(index) != token {
+		return lua.LString(token)
+	}
+
+	return lua.LNumber(index + 1)
+}
+
+// proxyKey returns the key token refers to in p.
+func proxyKey(p *proxy, token string) lua.LValue {
+	if _, ok := p.value.([]any); !ok {
+		return lua.LString(token)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || strconv.Itoa(index) != token {
+		return lua.LNil
+	}
+
+	return lua.LNumber(index + 1)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped reference
+// tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w %q: must start with /", errInvalidPointer, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// formatPointer is the inverse of parsePointer.
+func formatPointer(tokens []string) string {
+	var sb strings.Builder
+
+	for _, token := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(escapePointerToken(token))
+	}
+
+	return sb.String()
+}
+
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// arrayIndex parses token as an index into an array of the given length.
+// When allowEnd is set, the index may be equal to length and "-" refers to the
+// end of the array.
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w %q", errInvalidIndex, token)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("%w %q", errInvalidIndex, token)
+	}
+
+	if index > length || (index == length && !allowEnd) {
+		return 0, fmt.Errorf("%w %q: out of range", errInvalidIndex, token)
+	}
+
+	return index, nil
+}
+
+func apiPointerGet(L *lua.LState) int {
+	value := L.CheckAny(1)
+	pointer := L.CheckString(2)
+
+	result, err := GetPointer(value, pointer)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(result)
+
+	return 1
+}
+
+func apiPointerSet(L *lua.LState) int {
+	value := L.CheckTable(1)
+	pointer := L.CheckString(2)
+	v := L.CheckAny(3)
+
+	err := SetPointer(L, value, pointer, v)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LTrue)
+
+	return 1
+}
+
+func apiPointerRemove(L *lua.LState) int {
+	value := L.CheckTable(1)
+	pointer := L.CheckString(2)
+
+	removed, err := RemovePointer(value, pointer)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(removed)
+
+	return 1
+}
+
+var pointerAPI = map[string]lua.LGFunction{
+	"get":    apiPointerGet,
+	"remove": apiPointerRemove,
+	"set":    apiPointerSet,
+}
@@ -0,0 +1,137 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestPointerLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.pointer) == "table")
+	assert(type(json.pointer.get) == "function")
+	assert(type(json.pointer.set) == "function")
+	assert(type(json.pointer.remove) == "function")
+
+	local obj = json.decode('{"spec":{"template":{"spec":{"containers":[{"name":"a","image":"a:1"},{"name":"b","image":"b:1"}]}}},"metadata":{"annotations":{"a/b":"x","m~n":"y"}}}')
+
+	-- Test get
+	assert(json.pointer.get(obj, "/spec/template/spec/containers/0/image") == "a:1")
+	assert(json.pointer.get(obj, "/spec/template/spec/containers/1/name") == "b")
+	assert(json.pointer.get(obj, "/metadata/annotations/a~1b") == "x")
+	assert(json.pointer.get(obj, "/metadata/annotations/m~0n") == "y")
+	assert(json.pointer.get(obj, "") == obj)
+
+	local value, err = json.pointer.get(obj, "/status/conditions/0")
+	assert(value == nil)
+	assert(err == "path not found at /status", err)
+
+	local _, err = json.pointer.get(obj, "/spec/template/spec/containers/5")
+	assert(err == "path not found at /spec/template/spec/containers/5", err)
+
+	local _, err = json.pointer.get(obj, "spec")
+	assert(string.find(err, "invalid JSON pointer"), err)
+
+	-- Test set
+	assert(json.pointer.set(obj, "/spec/template/spec/containers/0/image", "a:2") == true)
+	assert(obj.spec.template.spec.containers[1].image == "a:2")
+
+	assert(json.pointer.set(obj, "/spec/template/spec/containers/-", {name = "c"}))
+	assert(obj.spec.template.spec.containers[3].name == "c")
+
+	assert(json.pointer.set(obj, "/status/phase", "Running"))
+	assert(obj.status.phase == "Running")
+
+	local _, err = json.pointer.set(obj, "/spec/template/spec/containers/9", {})
+	assert(string.find(err, "out of range"), err)
+
+	local _, err = json.pointer.set(obj, "/status/phase/value", 1)
+	assert(err == "/status/phase is not a table", err)
+
+	-- Test remove
+	local removed = json.pointer.remove(obj, "/spec/template/spec/containers/0")
+	assert(removed.name == "a")
+	assert(#obj.spec.template.spec.containers == 2)
+	assert(obj.spec.template.spec.containers[1].name == "b")
+
+	assert(json.pointer.remove(obj, "/status/phase") == "Running")
+	assert(obj.status.phase == nil)
+
+	local _, err = json.pointer.remove(obj, "/status/phase")
+	assert(err == "path not found at /status/phase", err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetPointer(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		pointer  string
+		expected lua.LValue
+		wantErr  string
+	}{
+		{
+			name:     "object member",
+			input:    `{"a":{"b":"c"}}`,
+			pointer:  "/a/b",
+			expected: lua.LString("c"),
+		},
+		{
+			name:     "array element",
+			input:    `{"a":[10,20]}`,
+			pointer:  "/a/1",
+			expected: lua.LNumber(20),
+		},
+		{
+			name:     "numeric object key",
+			input:    `{"0":"zero"}`,
+			pointer:  "/0",
+			expected: lua.LString("zero"),
+		},
+		{
+			name:    "leading zero index",
+			input:   `[1,2]`,
+			pointer: "/01",
+			wantErr: "path not found at /01",
+		},
+		{
+			name:    "index into scalar",
+			input:   `{"a":1}`,
+			pointer: "/a/b",
+			wantErr: "path not found at /a/b",
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := luajson.Decode(L, []byte(tt.input))
+			require.NoError(t, err)
+
+			result, err := luajson.GetPointer(value, tt.pointer)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// proxyTypeName is the registry name of the metatable of proxies.
+const proxyTypeName = "json.proxy"
+
+// proxy is the Value of a proxy userdata. It holds a map[string]any or an
+// []any and the Lua values of the elements accessed so far.
+type proxy struct {
+	// L is the state the proxy was created in, which converts the elements
+	// looked up outside of metamethods, as by JSONPath queries and pointers.
+	L     *lua.LState
+	value any
+	opts  DecodeOptions
+	cache map[lua.LValue]lua.LValue
+	keys  []string
+}
+
+// NewProxy returns a read-only userdata standing for obj, whose elements are
+// converted to Lua values only when a script accesses them. Nested objects and
+// arrays are proxies as well. Proxies support indexing, the length operator
+// and iteration through a __pairs metamethod, honoured by json.pairs, and
+// encode to the JSON of the Go value they stand for. QueryJSONPath and
+// GetPointer look into them as into tables. obj must not be modified while the
+// proxy is in use.
+func NewProxy(L *lua.LState, obj map[string]any) *lua.LUserData {
+	return NewProxyWithOptions(L, obj, DecodeOptions{})
+}
+
+// NewProxyWithOptions is like NewProxy, converting elements with
+// ToLuaWithOptions and opts.
+func NewProxyWithOptions(L *lua.LState, obj map[string]any, opts DecodeOptions) *lua.LUserData {
+	return newProxy(L, obj, opts)
+}
+
+func newProxy(L *lua.LState, value any, opts DecodeOptions) *lua.LUserData {
+	ud := L.NewUserData()
+	ud.Value = &proxy{L: L, value: value, opts: opts, cache: make(map[lua.LValue]lua.LValue)}
+	ud.Metatable = proxyMetatable(L)
+
+	return ud
+}
+
+func proxyMetatable(L *lua.LState) *lua.LTable {
+	mt := L.GetTypeMetatable(proxyTypeName)
+	if tbl, ok := mt.(*lua.LTable); ok {
+		return tbl
+	}
+
+	tbl := L.NewTypeMetatable(proxyTypeName)
+	L.SetFuncs(tbl, map[string]lua.LGFunction{
+		"__index":    proxyIndex,
+		"__newindex": proxyNewIndex,
+		"__len":      proxyLen,
+		"__pairs":    proxyPairs,
+	})
+
+	return tbl
+}
+
+func checkProxy(L *lua.LState, n int) *proxy {
+	p, ok := L.CheckUserData(n).Value.(*proxy)
+	if !ok {
+		L.ArgError(n, "JSON proxy expected")
+	}
+
+	return p
+}
+
+// asProxy returns the proxy value stands for, if it is one.
+func asProxy(value lua.LValue) (*proxy, bool) {
+	ud, ok := value.(*lua.LUserData)
+	if !ok {
+		return nil, false
+	}
+
+	p, ok := ud.Value.(*proxy)
+
+	return p, ok
+}
+
+// element returns the Lua value of the element at key, or nil if there is
+// none, raising conversion errors in L.
+func (p *proxy) element(L *lua.LState, key lua.LValue) lua.LValue {
+	value, err := p.lookup(L, key)
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+
+	return value
+}
+
+// lookup returns the Lua value of the element at key, or nil if there is
+// none.
+func (p *proxy) lookup(L *lua.LState, key lua.LValue) (lua.LValue, error) {
+	if value, ok := p.cache[key]; ok {
+		return value, nil
+	}
+
+	var item any
+
+	switch converted := p.value.(type) {
+	case map[string]any:
+		name, ok := key.(lua.LString)
+		if !ok {
+			return lua.LNil, nil
+		}
+
+		item, ok = converted[string(name)]
+		if !ok {
+			return lua.LNil, nil
+		}
+	case []any:
+		index, ok := key.(lua.LNumber)
+		if !ok || int(index) < 1 || int(index) > len(converted) || lua.LNumber(int(index)) != index {
+			return lua.LNil, nil
+		}
+
+		item = converted[int(index)-1]
+	}
+
+	var value lua.LValue
+
+	switch item.(type) {
+	case map[string]any, []any:
+		value = newProxy(L, item, p.opts)
+	default:
+		var err error
+
+		value, err = ToLuaWithOptions(L, item, p.opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.cache[key] = value
+
+	return value, nil
+}
+
+// table returns a table holding the elements of p, marked as an object or an
+// array, for code that walks tables. Nested objects and arrays stay proxies.
+func (p *proxy) table() (*lua.LTable, error) {
+	tbl := p.L.NewTable()
+
+	for key := p.next(lua.LNil); key != lua.LNil; key = p.next(key) {
+		value, err := p.lookup(p.L, key)
+		if err != nil {
+			return nil, err
+		}
+
+		tbl.RawSet(key, value)
+	}
+
+	if _, ok := p.value.([]any); ok {
+		return MarkArray(p.L, tbl), nil
+	}
+
+	return MarkObject(p.L, tbl), nil
+}
+
+// next returns the key following key in iteration order, which is the order
+// of indices for arrays and of sorted keys for objects, or nil at the end.
+func (p *proxy) next(key lua.LValue) lua.LValue {
+	switch converted := p.value.(type) {
+	case map[string]any:
+		if p.keys == nil {
+			p.keys = make([]string, 0, len(converted))
+			for name := range converted {
+				p.keys = append(p.keys, name)
+			}
+
+			sort.Strings(p.keys)
+		}
+
+		i := 0
+
+		if name, ok := key.(lua.LString); ok {
+			i = sort.SearchStrings(p.keys, string(name)) + 1
+		}
+
+		if i < len(p.keys) {
+			return lua.LString(p.keys[i])
+		}
+	case []any:
+		index, _ := key.(lua.LNumber)
+		if int(index) < len(converted) {
+			return index + 1
+		}
+	}
+
+	return lua.LNil
+}
+
+func proxyIndex(L *lua.LState) int {
+	p := checkProxy(L, 1)
+
+	L.Push(p.element(L, L.CheckAny(2)))
+
+	return 1
+}
+
+func proxyNewIndex(L *lua.LState) int {
+	L.RaiseError("cannot modify a read-only JSON proxy")
+
+	return 0
+}
+
+func proxyLen(L *lua.LState) int {
+	p := checkProxy(L, 1)
+
+	length := 0
+	if arr, ok := p.value.([]any); ok {
+		length = len(arr)
+	}
+
+	L.Push(lua.LNumber(length))
+
+	return 1
+}
+
+func proxyPairs(L *lua.LState) int {
+	checkProxy(L, 1)
+
+	L.Push(L.NewFunction(proxyNext))
+	L.Push(L.Get(1))
+	L.Push(lua.LNil)
+
+	return 3
+}
+
+func proxyNext(L *lua.LState) int {
+	p := checkProxy(L, 1)
+
+	key := p.next(L.Get(2))
+	if key == lua.LNil {
+		L.Push(lua.LNil)
+
+		return 1
+	}
+
+	L.Push(key)
+	L.Push(p.element(L, key))
+
+	return 2
+}
+
+func tableNext(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+
+	key, value := tbl.Next(L.Get(2))
+	if key == lua.LNil {
+		L.Push(lua.LNil)
+
+		return 1
+	}
+
+	L.Push(key)
+	L.Push(value)
+
+	return 2
+}
+
+// apiPairs returns an iterator over value like pairs, calling the __pairs
+// metamethod of value if it has one.
+func apiPairs(L *lua.LState) int {
+	value := L.CheckAny(1)
+
+	if fn := L.GetMetaField(value, "__pairs"); fn != lua.LNil {
+		L.Push(fn)
+		L.Push(value)
+		L.Call(1, 3)
+
+		return 3
+	}
+
+	L.CheckTable(1)
+	L.Push(L.NewFunction(tableNext))
+	L.Push(value)
+	L.Push(lua.LNil)
+
+	return 3
+}
+
+// marshalJSON returns the JSON encoding of the value p stands for, formatting
+// numbers and strings as Encode does.
+func (p *proxy) marshalJSON(escapeHTML bool) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := writeGoJSON(&buf, p.value, escapeHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeGoJSON(buf *bytes.Buffer, value any, escapeHTML bool) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch converted := value.(type) {
+	case nil:
+		data = []byte(`null`)
+	case string:
+		data, err = encodeString(converted, escapeHTML)
+	case float64:
+		data, err = encodeNumber(lua.LNumber(converted))
+	case int64:
+		data = strconv.AppendInt(nil, converted, 10)
+	case json.Number:
+		data = []byte(converted)
+	case map[string]any:
+		keys := make([]string, 0, len(converted))
+		for key := range converted {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			name, err := encodeString(key, escapeHTML)
+			if err != nil {
+				return err
+			}
+
+			buf.Write(name)
+			buf.WriteByte(':')
+
+			err = writeGoJSON(buf, converted[key], escapeHTML)
+			if err != nil {
+				return withPathSegment(err, keySegment(key))
+			}
+		}
+
+		buf.WriteByte('}')
+
+		return nil
+	case []any:
+		buf.WriteByte('[')
+
+		for i, item := range converted {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			err := writeGoJSON(buf, item, escapeHTML)
+			if err != nil {
+				return withPathSegment(err, "["+strconv.Itoa(i+1)+"]")
+			}
+		}
+
+		buf.WriteByte(']')
+
+		return nil
+	default:
+		data, err = encodeGoValue(converted, escapeHTML)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	buf.Write(data)
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package json_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func newProxyState(t *testing.T) *lua.LState {
+	t.Helper()
+
+	var obj map[string]any
+
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"kind": "Deployment",
+		"metadata": {"name": "web", "labels": {"app": "web", "tier": "<frontend>"}},
+		"spec": {
+			"replicas": 3,
+			"paused": false,
+			"ratio": 0.5,
+			"containers": [{"name": "nginx", "image": "nginx:1.25"}, {"name": "sidecar"}],
+			"selector": {},
+			"empty": null
+		}
+	}`), &obj))
+
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+
+	luajson.Preload(L)
+	L.SetGlobal("obj", luajson.NewProxy(L, obj))
+
+	return L
+}
+
+func TestProxy(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected lua.LValue
+	}{
+		{
+			name:     "string",
+			script:   `return obj.kind`,
+			expected: lua.LString("Deployment"),
+		},
+		{
+			name:     "nested",
+			script:   `return obj.metadata.labels.app`,
+			expected: lua.LString("web"),
+		},
+		{
+			name:     "number",
+			script:   `return obj.spec.replicas + 1`,
+			expected: lua.LNumber(4),
+		},
+		{
+			name:     "bool",
+			script:   `return obj.spec.paused`,
+			expected: lua.LFalse,
+		},
+		{
+			name:     "array element",
+			script:   `return obj.spec.containers[2].name`,
+			expected: lua.LString("sidecar"),
+		},
+		{
+			name:     "length",
+			script:   `return #obj.spec.containers`,
+			expected: lua.LNumber(2),
+		},
+		{
+			name:     "missing",
+			script:   `return obj.status == nil and obj.spec.containers[3] == nil and obj.spec.containers.name == nil`,
+			expected: lua.LTrue,
+		},
+		{
+			name:     "null",
+			script:   `return obj.spec.empty`,
+			expected: lua.LNil,
+		},
+		{
+			name:     "same proxy",
+			script:   `return obj.metadata == obj.metadata`,
+			expected: lua.LTrue,
+		},
+		{
+			name: "object iteration",
+			script: `
+			local json = require("json")
+			local keys = {}
+			for k, v in json.pairs(obj.spec) do
+				keys[#keys + 1] = k
+			end
+			return table.concat(keys, ",")`,
+			expected: lua.LString("containers,empty,paused,ratio,replicas,selector"),
+		},
+		{
+			name: "array iteration",
+			script: `
+			local json = require("json")
+			local names = {}
+			for i, c in json.pairs(obj.spec.containers) do
+				names[i] = c.name
+			end
+			return table.concat(names, ",")`,
+			expected: lua.LString("nginx,sidecar"),
+		},
+		{
+			name: "table iteration",
+			script: `
+			local json = require("json")
+			local sum = 0
+			for _, v in json.pairs({1, 2, 3}) do
+				sum = sum + v
+			end
+			return sum`,
+			expected: lua.LNumber(6),
+		},
+		{
+			name: "encode",
+			script: `
+			local json = require("json")
+			return json.encode(obj.metadata)`,
+			expected: lua.LString(`{"labels":{"app":"web","tier":"\u003cfrontend\u003e"},"name":"web"}`),
+		},
+		{
+			name: "jsonpath",
+			script: `
+			local json = require("json")
+			local names = json.jsonpath(obj, "{.spec.containers[*].name}")
+			local images = json.jsonpath(obj, '{..containers[?(@.name=="nginx")].image}')
+			return table.concat(names, ",") .. " " .. images[1]`,
+			expected: lua.LString("nginx,sidecar nginx:1.25"),
+		},
+		{
+			name: "pointer",
+			script: `
+			local json = require("json")
+			local _, err = json.pointer.get(obj, "/spec/containers/2")
+			return json.pointer.get(obj, "/spec/containers/1/name") .. " " .. err`,
+			expected: lua.LString("sidecar path not found at /spec/containers/2"),
+		},
+		{
+			name: "encode inside table",
+			script: `
+			local json = require("json")
+			return json.encode({name = obj.metadata.name, selector = obj.spec.selector}, {escapeHTML = false})`,
+			expected: lua.LString(`{"name":"web","selector":{}}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			L := newProxyState(t)
+
+			require.NoError(t, L.DoString(tt.script))
+			assert.Equal(t, tt.expected, L.Get(-1))
+		})
+	}
+}
+
+func TestProxyEncodeGoValues(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	luajson.Preload(L)
+	L.SetGlobal("obj", luajson.NewProxy(L, map[string]any{
+		"tags":   []string{"<a&b>"},
+		"labels": map[string]string{"tier": "<frontend>"},
+	}))
+
+	require.NoError(t, L.DoString(`
+	local json = require("json")
+	return json.encode(obj, {escapeHTML = false}), json.encode(obj)`))
+	assert.Equal(t, lua.LString(`{"labels":{"tier":"<frontend>"},"tags":["<a&b>"]}`), L.Get(-2))
+	assert.Equal(t, lua.LString(`{"labels":{"tier":"\u003cfrontend\u003e"},"tags":["\u003ca\u0026b\u003e"]}`), L.Get(-1))
+}
+
+func TestProxyReadOnly(t *testing.T) {
+	L := newProxyState(t)
+
+	err := L.DoString(`obj.spec.replicas = 5`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot modify a read-only JSON proxy")
+
+	err = L.DoString(`require("json").pairs(42)`)
+	require.Error(t, err)
+}
+
+func TestProxyGoConversions(t *testing.T) {
+	L := newProxyState(t)
+
+	require.NoError(t, L.DoString(`spec = obj.spec`))
+
+	value, err := luajson.FromLua(L.GetGlobal("spec"))
+	require.NoError(t, err)
+
+	// The result is a copy, safe to modify.
+	containers := value.(map[string]any)["containers"].([]any)
+	containers[0].(map[string]any)["name"] = "changed"
+
+	assert.Equal(t, map[string]any{
+		"replicas": int64(3),
+		"paused":   false,
+		"ratio":    0.5,
+		"containers": []any{
+			map[string]any{"name": "changed", "image": "nginx:1.25"},
+			map[string]any{"name": "sidecar"},
+		},
+		"selector": map[string]any{},
+		"empty":    nil,
+	}, value)
+
+	require.NoError(t, L.DoString(`name = obj.spec.containers[1].name`))
+	assert.Equal(t, lua.LString("nginx"), L.GetGlobal("name"))
+
+	var spec struct {
+		Replicas   int `json:"replicas"`
+		Containers []struct {
+			Name string `json:"name"`
+		} `json:"containers"`
+	}
+
+	require.NoError(t, luajson.Unmarshal(L.GetGlobal("spec"), &spec))
+	assert.Equal(t, 3, spec.Replicas)
+	assert.Len(t, spec.Containers, 2)
+}
@@ -0,0 +1,351 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"sigs.k8s.io/yaml"
+)
+
+// schemaTypeName is the registry name of the metatable of compiled schemas.
+const schemaTypeName = "json.schema"
+
+// schemaURL is the location compiled schemas are registered at. Schemas may
+// only refer to themselves, as no other resource can be loaded.
+const schemaURL = "urn:glua-json:schema"
+
+var schemaPrinter = message.NewPrinter(language.English)
+
+// SchemaOptions controls how a schema is compiled.
+type SchemaOptions struct {
+	// OpenAPI reads the schema as an OpenAPI v3 schema, such as the
+	// openAPIV3Schema of a CustomResourceDefinition: nullable, boolean
+	// exclusiveMinimum and exclusiveMaximum, and x-kubernetes-int-or-string
+	// are translated to their JSON Schema equivalents. Other OpenAPI and
+	// Kubernetes extensions are ignored.
+	OpenAPI bool
+}
+
+// SchemaViolation is a value that does not satisfy a schema.
+type SchemaViolation struct {
+	// Path is the JSON Pointer of the value, such as "/spec/replicas", or the
+	// empty string for the validated value itself.
+	Path    string
+	Message string
+}
+
+// Schema is a compiled JSON Schema, which can validate any number of values.
+type Schema struct {
+	schema *jsonschema.Schema
+}
+
+// CompileSchema compiles a JSON Schema. schema is either a table or a JSON or
+// YAML string. Schemas follow draft 2020-12 unless they declare another draft
+// with $schema. References to other documents are not resolved.
+func CompileSchema(schema lua.LValue, opts SchemaOptions) (*Schema, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if str, ok := schema.(lua.LString); ok {
+		data, err = yaml.YAMLToJSON([]byte(str))
+	} else {
+		data, err = Encode(schema)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OpenAPI {
+		doc = fromOpenAPI(doc)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.UseLoader(jsonschema.SchemeURLLoader{})
+
+	err = compiler.AddResource(schemaURL, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema{schema: compiled}, nil
+}
+
+// fromOpenAPI translates the OpenAPI v3 keywords of the schema doc.
+func fromOpenAPI(doc any) any {
+	switch converted := doc.(type) {
+	case map[string]any:
+		for key, value := range converted {
+			converted[key] = fromOpenAPI(value)
+		}
+
+		if converted["nullable"] == true {
+			if typ, ok := converted["type"].(string); ok {
+				converted["type"] = []any{typ, "null"}
+			}
+		}
+
+		// OpenAPI v3 exclusive bounds are flags on minimum and maximum,
+		// where JSON Schema has them hold the bound.
+		for exclusive, bound := range map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"} {
+			switch converted[exclusive] {
+			case true:
+				if limit, ok := converted[bound]; ok {
+					converted[exclusive] = limit
+					delete(converted, bound)
+				} else {
+					delete(converted, exclusive)
+				}
+			case false:
+				delete(converted, exclusive)
+			}
+		}
+
+		if converted["x-kubernetes-int-or-string"] == true {
+			if _, ok := converted["type"]; !ok {
+				converted["type"] = []any{"integer", "string"}
+			}
+		}
+	case []any:
+		for i, item := range converted {
+			converted[i] = fromOpenAPI(item)
+		}
+	}
+
+	return doc
+}
+
+// Validate returns the violations of the schema by value, sorted by path, or
+// none if value is valid. Unmarked empty tables are valid objects as well as
+// arrays. It returns an error if value cannot be encoded.
+func (s *Schema) Validate(value lua.LValue) ([]SchemaViolation, error) {
+	return s.validate(value, EncodeOptions{})
+}
+
+func (s *Schema) validate(value lua.LValue, opts EncodeOptions) ([]SchemaViolation, error) {
+	data, err := EncodeWithOptions(value, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarked empty tables encode as [], although they may as well stand
+	// for {}: those the schema wants as objects are retried as {}.
+	empty := emptyTables(value, nil, make(map[string]bool), make(map[*lua.LTable]bool))
+
+	var validationErr *jsonschema.ValidationError
+
+	for {
+		err = s.schema.Validate(instance)
+		if err == nil {
+			return nil, nil
+		}
+
+		if !errors.As(err, &validationErr) {
+			return nil, err
+		}
+
+		retried := false
+
+		for _, location := range wantedObjects(validationErr, nil) {
+			pointer := formatPointer(location)
+			if !empty[pointer] {
+				continue
+			}
+
+			delete(empty, pointer)
+
+			instance, err = pointerReplace(instance, location, map[string]any{})
+			if err != nil {
+				return nil, err
+			}
+
+			retried = true
+		}
+
+		if !retried {
+			break
+		}
+	}
+
+	violations := schemaViolations(validationErr, nil)
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Path < violations[j].Path
+	})
+
+	return violations, nil
+}
+
+// emptyTables adds the JSON Pointers of the unmarked empty tables of value,
+// found at the path tokens, to pointers and returns it.
+func emptyTables(value lua.LValue, tokens []string, pointers map[string]bool, visited map[*lua.LTable]bool) map[string]bool {
+	tbl, ok := value.(*lua.LTable)
+	if !ok || visited[tbl] {
+		return pointers
+	}
+
+	visited[tbl] = true
+
+	if key, _ := tbl.Next(lua.LNil); key == lua.LNil {
+		if tableType(tbl) == "" {
+			pointers[formatPointer(tokens)] = true
+		}
+
+		return pointers
+	}
+
+	tbl.ForEach(func(key, child lua.LValue) {
+		token := key.String()
+		if n, ok := key.(lua.LNumber); ok {
+			token = strconv.Itoa(int(n) - 1)
+		}
+
+		pointers = emptyTables(child, append(tokens[:len(tokens):len(tokens)], token), pointers, visited)
+	})
+
+	return pointers
+}
+
+// wantedObjects appends the locations of the arrays err reports where objects
+// are wanted to locations.
+func wantedObjects(err *jsonschema.ValidationError, locations [][]string) [][]string {
+	if typ, ok := err.ErrorKind.(*kind.Type); ok && typ.Got == "array" && slices.Contains(typ.Want, "object") {
+		locations = append(locations, err.InstanceLocation)
+	}
+
+	for _, cause := range err.Causes {
+		locations = wantedObjects(cause, locations)
+	}
+
+	return locations
+}
+
+// schemaViolations appends the violations err is made of to violations.
+func schemaViolations(err *jsonschema.ValidationError, violations []SchemaViolation) []SchemaViolation {
+	if len(err.Causes) == 0 {
+		return append(violations, SchemaViolation{
+			Path:    formatPointer(err.InstanceLocation),
+			Message: err.ErrorKind.LocalizedString(schemaPrinter),
+		})
+	}
+
+	for _, cause := range err.Causes {
+		violations = schemaViolations(cause, violations)
+	}
+
+	return violations
+}
+
+func checkSchema(L *lua.LState, n int) *Schema {
+	ud := L.CheckUserData(n)
+
+	schema, ok := ud.Value.(*Schema)
+	if !ok {
+		L.ArgError(n, "JSON schema expected")
+	}
+
+	return schema
+}
+
+func schemaMetatable(L *lua.LState) *lua.LTable {
+	mt := L.GetTypeMetatable(schemaTypeName)
+	if tbl, ok := mt.(*lua.LTable); ok {
+		return tbl
+	}
+
+	tbl := L.NewTypeMetatable(schemaTypeName)
+	tbl.RawSetString("__index", L.SetFuncs(L.NewTable(), schemaMethods))
+
+	return tbl
+}
+
+func apiCompileSchema(L *lua.LState) int {
+	schema := L.CheckAny(1)
+	tbl := L.OptTable(2, nil)
+
+	if _, ok := schema.(lua.LString); !ok {
+		L.CheckTable(1)
+	}
+
+	var opts SchemaOptions
+	if tbl != nil {
+		opts.OpenAPI = lua.LVAsBool(tbl.RawGetString("openAPI"))
+	}
+
+	compiled, err := CompileSchema(schema, opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	ud := L.NewUserData()
+	ud.Value = compiled
+	ud.Metatable = schemaMetatable(L)
+
+	L.Push(ud)
+
+	return 1
+}
+
+func schemaValidate(L *lua.LState) int {
+	schema := checkSchema(L, 1)
+	value := L.CheckAny(2)
+
+	violations, err := schema.validate(value, EncodeOptions{State: L})
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("cannot validate value: %v", err)))
+
+		return 2
+	}
+
+	list := L.CreateTable(len(violations), 0)
+
+	for _, violation := range violations {
+		item := L.CreateTable(0, 2)
+		item.RawSetString("path", lua.LString(violation.Path))
+		item.RawSetString("message", lua.LString(violation.Message))
+		list.Append(item)
+	}
+
+	L.Push(list)
+
+	return 1
+}
+
+var schemaMethods = map[string]lua.LGFunction{
+	"validate": schemaValidate,
+}
+
+var schemaAPI = map[string]lua.LGFunction{
+	"compile": apiCompileSchema,
+}
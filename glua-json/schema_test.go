@@ -0,0 +1,165 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestSchemaLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.schema.compile) == "function")
+
+	local schema = json.schema.compile({
+		type = "object",
+		required = {"image", "replicas"},
+		properties = {
+			image = {type = "string", minLength = 1},
+			replicas = {type = "integer", minimum = 1},
+			ports = {type = "array", items = {type = "integer", maximum = 65535}},
+		},
+	})
+	assert(schema ~= nil)
+
+	-- Test a valid value
+	local violations = schema:validate({image = "nginx", replicas = 2, ports = {80, 443}})
+	assert(#violations == 0)
+
+	-- Test violations
+	local violations = schema:validate({image = "", ports = {80, 70000}})
+	assert(#violations == 3, json.encode(violations))
+	assert(violations[1].path == "", violations[1].path)
+	assert(string.find(violations[1].message, "replicas"), violations[1].message)
+	assert(violations[2].path == "/image")
+	assert(violations[3].path == "/ports/1")
+
+	-- Test empty tables, which may be objects or arrays
+	local schema = json.schema.compile("properties:\n  labels: {type: object}\n  args: {type: array}\n")
+	assert(#schema:validate(json.decode('{"labels":{},"args":[]}')) == 0)
+	assert(#schema:validate({labels = {}, args = {}}) == 0)
+	assert(#schema:validate({labels = json.array()}) == 1)
+	assert(#schema:validate({args = json.object()}) == 1)
+
+	-- Test a YAML schema
+	local schema = json.schema.compile("type: string\nenum: [a, b]\n")
+	assert(#schema:validate("a") == 0)
+	assert(#schema:validate("c") == 1)
+
+	-- Test errors
+	local schema, err = json.schema.compile({type = "unknown"})
+	assert(schema == nil)
+	assert(err ~= nil)
+
+	local schema, err = json.schema.compile({["$ref"] = "file:///etc/passwd"})
+	assert(schema == nil)
+	assert(err ~= nil)
+
+	local schema = json.schema.compile(json.object())
+	local violations, err = schema:validate(function() end)
+	assert(violations == nil)
+	assert(string.find(err, "^cannot validate value: "), err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSchemaOpenAPI(t *testing.T) {
+	const crdSchema = `
+type: object
+properties:
+  spec:
+    type: object
+    required: [selector]
+    properties:
+      selector:
+        type: object
+        nullable: true
+        x-kubernetes-preserve-unknown-fields: true
+      port:
+        x-kubernetes-int-or-string: true
+      replicas:
+        type: integer
+        format: int32
+`
+
+	L := lua.NewState()
+	defer L.Close()
+
+	schema, err := luajson.CompileSchema(lua.LString(crdSchema), luajson.SchemaOptions{OpenAPI: true})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{
+			name:  "valid",
+			value: `{"spec":{"selector":{"app":"web"},"port":"http","replicas":3}}`,
+		},
+		{
+			name:  "nullable",
+			value: `{"spec":{"selector":null,"port":8080}}`,
+		},
+		{
+			name:     "invalid",
+			value:    `{"spec":{"port":true,"replicas":"3"}}`,
+			expected: []string{"/spec", "/spec/port", "/spec/replicas"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := luajson.DecodeWithOptions(L, []byte(tt.value), luajson.DecodeOptions{PreserveNull: true})
+			require.NoError(t, err)
+
+			violations, err := schema.Validate(value)
+			require.NoError(t, err)
+
+			paths := make([]string, 0, len(violations))
+			for _, violation := range violations {
+				paths = append(paths, violation.Path)
+				assert.NotEmpty(t, violation.Message)
+			}
+
+			if tt.expected == nil {
+				assert.Empty(t, paths)
+			} else {
+				assert.Equal(t, tt.expected, paths)
+			}
+		})
+	}
+
+	// Without OpenAPI, nullable is ignored.
+	schema, err = luajson.CompileSchema(lua.LString(crdSchema), luajson.SchemaOptions{})
+	require.NoError(t, err)
+
+	value, err := luajson.DecodeWithOptions(L, []byte(`{"spec":{"selector":null}}`), luajson.DecodeOptions{PreserveNull: true})
+	require.NoError(t, err)
+
+	violations, err := schema.Validate(value)
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "/spec/selector", violations[0].Path)
+}
+
+func TestSchemaOpenAPIExclusiveBounds(t *testing.T) {
+	schema, err := luajson.CompileSchema(lua.LString("type: integer\nminimum: 0\nexclusiveMinimum: true\nmaximum: 10\nexclusiveMaximum: false\n"), luajson.SchemaOptions{OpenAPI: true})
+	require.NoError(t, err)
+
+	for value, valid := range map[int]bool{-1: false, 0: false, 1: true, 10: true, 11: false} {
+		violations, err := schema.Validate(lua.LNumber(value))
+		require.NoError(t, err)
+		assert.Equal(t, valid, len(violations) == 0, "value %d", value)
+	}
+}
@@ -0,0 +1,423 @@
+package json
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var (
+	unmarshalerType     = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// UnmarshalError reports a Lua value that could not be unmarshalled and where
+// it is.
+type UnmarshalError struct {
+	// Path locates the value like EncodeError.Path.
+	Path string
+	Err  error
+}
+
+func (e *UnmarshalError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+
+	return e.Err.Error() + " at " + e.Path
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// UnmarshalTypeError reports a Lua value that does not fit the Go type it is
+// unmarshalled into.
+type UnmarshalTypeError struct {
+	// Value describes the Lua value, such as "string" or "number 1.5".
+	Value string
+	Type  reflect.Type
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return "cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+}
+
+// withUnmarshalPath prepends segment to the path of the unmarshal error err.
+func withUnmarshalPath(err error, segment string) error {
+	var unmarshalErr *UnmarshalError
+	if errors.As(err, &unmarshalErr) {
+		unmarshalErr.Path = segment + unmarshalErr.Path
+
+		return unmarshalErr
+	}
+
+	return &UnmarshalError{Path: segment, Err: err}
+}
+
+// Unmarshal stores value in the Go value target points to, following the
+// rules of encoding/json as if value were encoded with Encode and the result
+// decoded with json.Unmarshal, but without the round-trip. Struct fields are
+// matched by their json tags, keys without a matching field are ignored.
+// Errors are *UnmarshalError values locating the offending value.
+func Unmarshal(value lua.LValue, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("cannot unmarshal into %T: target must be a non-nil pointer", target)
+	}
+
+	state := &encodeState{
+		opts:    EncodeOptions{SortKeys: true},
+		visited: make(map[*lua.LTable]bool),
+	}
+
+	err := jsonValue{value, state}.unmarshal(v.Elem())
+	if err != nil {
+		return withUnmarshalPath(err, "")
+	}
+
+	return nil
+}
+
+func (j jsonValue) unmarshal(v reflect.Value) error {
+	if j.LValue == lua.LNil || IsNull(j.LValue) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			v.SetZero()
+		}
+
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return j.unmarshal(v.Elem())
+	}
+
+	if unmarshaler, ok := asInterface[json.Unmarshaler](v, unmarshalerType); ok {
+		data, err := EncodeWithOptions(j.LValue, j.state.opts)
+		if err != nil {
+			return err
+		}
+
+		return unmarshaler.UnmarshalJSON(data)
+	}
+
+	// Proxies hold Go values already, which encoding/json converts directly.
+	if ud, ok := j.LValue.(*lua.LUserData); ok && v.Kind() != reflect.Interface {
+		if p, ok := ud.Value.(*proxy); ok {
+			data, err := p.marshalJSON(false)
+			if err != nil {
+				return err
+			}
+
+			return json.Unmarshal(data, v.Addr().Interface())
+		}
+	}
+
+	if str, ok := j.LValue.(lua.LString); ok {
+		if unmarshaler, ok := asInterface[encoding.TextUnmarshaler](v, textUnmarshalerType); ok {
+			return unmarshaler.UnmarshalText([]byte(str))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return j.typeError(v.Type())
+		}
+
+		value, err := j.toGo()
+		if err != nil {
+			return err
+		}
+
+		if value == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+
+		return nil
+	case reflect.Bool:
+		b, ok := j.LValue.(lua.LBool)
+		if !ok {
+			return j.typeError(v.Type())
+		}
+
+		v.SetBool(bool(b))
+
+		return nil
+	case reflect.String:
+		str, ok := j.LValue.(lua.LString)
+		if !ok {
+			return j.typeError(v.Type())
+		}
+
+		v.SetString(string(str))
+
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return j.unmarshalNumber(v)
+	case reflect.Slice:
+		if str, ok := j.LValue.(lua.LString); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			data, err := base64.StdEncoding.DecodeString(string(str))
+			if err != nil {
+				return err
+			}
+
+			v.SetBytes(data)
+
+			return nil
+		}
+
+		return j.unmarshalArray(v)
+	case reflect.Array:
+		return j.unmarshalArray(v)
+	case reflect.Map, reflect.Struct:
+		return j.unmarshalObject(v)
+	default:
+		return j.typeError(v.Type())
+	}
+}
+
+func (j jsonValue) typeError(typ reflect.Type) error {
+	return &UnmarshalTypeError{Value: j.LValue.Type().String(), Type: typ}
+}
+
+func (j jsonValue) unmarshalNumber(v reflect.Value) error {
+	var literal string
+
+	switch converted := j.LValue.(type) {
+	case lua.LNumber:
+		literal = strconv.FormatFloat(float64(converted), 'f', -1, 64)
+	case *lua.LUserData:
+		number, ok := largeNumber(converted)
+		if !ok {
+			return j.typeError(v.Type())
+		}
+
+		literal = number.String()
+	default:
+		return j.typeError(v.Type())
+	}
+
+	mismatch := &UnmarshalTypeError{Value: "number " + literal, Type: v.Type()}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(literal, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return mismatch
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(literal, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return mismatch
+		}
+
+		v.SetUint(n)
+	default:
+		f, err := strconv.ParseFloat(literal, 64)
+		if err != nil || math.IsInf(f, 0) || v.OverflowFloat(f) {
+			return mismatch
+		}
+
+		v.SetFloat(f)
+	}
+
+	return nil
+}
+
+func (j jsonValue) unmarshalArray(v reflect.Value) error {
+	tbl, ok := j.LValue.(*lua.LTable)
+	if !ok {
+		return j.typeError(v.Type())
+	}
+
+	shape, err := j.state.shape(tbl)
+	if err != nil {
+		return err
+	}
+
+	if !shape.array {
+		return &UnmarshalTypeError{Value: "object", Type: v.Type()}
+	}
+
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), shape.length, shape.length))
+	}
+
+	for i := range v.Len() {
+		if i >= shape.length {
+			v.Index(i).SetZero()
+
+			continue
+		}
+
+		err := jsonValue{tbl.RawGetInt(i + 1), j.state}.unmarshal(v.Index(i))
+		if err != nil {
+			return withUnmarshalPath(err, fmt.Sprintf("[%d]", i+1))
+		}
+	}
+
+	return nil
+}
+
+func (j jsonValue) unmarshalObject(v reflect.Value) error {
+	tbl, ok := j.LValue.(*lua.LTable)
+	if !ok {
+		return j.typeError(v.Type())
+	}
+
+	shape, err := j.state.shape(tbl)
+	if err != nil {
+		return err
+	}
+
+	// Empty tables are arrays unless marked, but fit objects all the same.
+	if shape.array && shape.length > 0 {
+		return &UnmarshalTypeError{Value: "array", Type: v.Type()}
+	}
+
+	names, values, err := objectEntries(tbl, shape.keys)
+	if err != nil {
+		return err
+	}
+
+	if v.Kind() == reflect.Map {
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(names)))
+		}
+
+		for _, name := range names {
+			key, err := mapKey(v.Type().Key(), name)
+			if err != nil {
+				return withUnmarshalPath(err, keySegment(name))
+			}
+
+			elem := reflect.New(v.Type().Elem()).Elem()
+
+			err = jsonValue{values[name], j.state}.unmarshal(elem)
+			if err != nil {
+				return withUnmarshalPath(err, keySegment(name))
+			}
+
+			v.SetMapIndex(key, elem)
+		}
+
+		return nil
+	}
+
+	fields := structFields(v.Type())
+
+	for _, name := range names {
+		field, ok := matchField(fields, name)
+		if !ok {
+			continue
+		}
+
+		fv, ok := fieldForSet(v, field.index)
+		if !ok {
+			continue
+		}
+
+		err := jsonValue{values[name], j.state}.unmarshal(fv)
+		if err != nil {
+			return withUnmarshalPath(err, keySegment(name))
+		}
+	}
+
+	return nil
+}
+
+// matchField returns the field named name, preferring an exact match to a
+// case-insensitive one, as encoding/json does.
+func matchField(fields []structField, name string) (structField, bool) {
+	for _, field := range fields {
+		if field.name == name {
+			return field, true
+		}
+	}
+
+	for _, field := range fields {
+		if strings.EqualFold(field.name, name) {
+			return field, true
+		}
+	}
+
+	return structField{}, false
+}
+
+// fieldForSet returns the field of v at index, allocating nil embedded
+// pointers on the way, or false if such a pointer cannot be set.
+func fieldForSet(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v, true
+}
+
+// mapKey converts the object key name to a key of type typ.
+func mapKey(typ reflect.Type, name string) (reflect.Value, error) {
+	key := reflect.New(typ)
+
+	if unmarshaler, ok := key.Interface().(encoding.TextUnmarshaler); ok && typ.Kind() != reflect.String {
+		err := unmarshaler.UnmarshalText([]byte(name))
+
+		return key.Elem(), err
+	}
+
+	mismatch := &UnmarshalTypeError{Value: "key " + strconv.Quote(name), Type: typ}
+
+	switch typ.Kind() {
+	case reflect.String:
+		key.Elem().SetString(name)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(name, 10, 64)
+		if err != nil || key.Elem().OverflowInt(n) {
+			return reflect.Value{}, mismatch
+		}
+
+		key.Elem().SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(name, 10, 64)
+		if err != nil || key.Elem().OverflowUint(n) {
+			return reflect.Value{}, mismatch
+		}
+
+		key.Elem().SetUint(n)
+	default:
+		return reflect.Value{}, mismatch
+	}
+
+	return key.Elem(), nil
+}
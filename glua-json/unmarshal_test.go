@@ -0,0 +1,186 @@
+package json_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+type healthCheck struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type condition struct {
+	Type               string    `json:"type"`
+	Ready              bool      `json:"ready"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+type resourceStatus struct {
+	healthCheck
+
+	Replicas   *int32            `json:"replicas"`
+	Ratio      float32           `json:"ratio"`
+	UID        uint64            `json:"uid"`
+	Conditions []condition       `json:"conditions"`
+	Labels     map[string]string `json:"labels"`
+	Ports      map[int]string    `json:"ports"`
+	Pair       [2]int            `json:"pair"`
+	Data       []byte            `json:"data"`
+	Extra      any               `json:"extra"`
+	Skipped    string            `json:"-"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	luajson.Preload(L)
+
+	require.NoError(t, L.DoString(`
+	local json = require("json")
+	result = {
+		status = "Degraded",
+		message = "1 of 3 replicas ready",
+		replicas = 3,
+		ratio = 0.5,
+		uid = json.decode("12345678901234567891", {useNumber = true}),
+		conditions = {
+			{type = "Available", ready = true, lastTransitionTime = "2024-01-02T03:04:05Z"},
+		},
+		labels = {app = "web"},
+		ports = {["80"] = "http"},
+		pair = {1},
+		data = "aGVsbG8=",
+		extra = {a = {1, 2}},
+		Skipped = "no",
+		unknown = true,
+	}`))
+
+	var status resourceStatus
+
+	err := luajson.Unmarshal(L.GetGlobal("result"), &status)
+	require.NoError(t, err)
+
+	replicas := int32(3)
+	assert.Equal(t, resourceStatus{
+		healthCheck: healthCheck{Status: "Degraded", Message: "1 of 3 replicas ready"},
+		Replicas:    &replicas,
+		Ratio:       0.5,
+		UID:         12345678901234567891,
+		Conditions: []condition{
+			{Type: "Available", Ready: true, LastTransitionTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+		Labels: map[string]string{"app": "web"},
+		Ports:  map[int]string{80: "http"},
+		Pair:   [2]int{1, 0},
+		Data:   []byte("hello"),
+		Extra:  map[string]any{"a": []any{int64(1), int64(2)}},
+	}, status)
+
+	// Test nil and empty tables
+	status = resourceStatus{Replicas: &replicas, Labels: map[string]string{"a": "b"}}
+
+	tbl := L.NewTable()
+	tbl.RawSetString("replicas", luajson.Null(L))
+	tbl.RawSetString("labels", L.NewTable())
+
+	require.NoError(t, luajson.Unmarshal(tbl, &status))
+	assert.Nil(t, status.Replicas)
+	assert.Equal(t, map[string]string{"a": "b"}, status.Labels)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	tests := []struct {
+		name     string
+		script   string
+		target   any
+		path     string
+		expected string
+	}{
+		{
+			name:     "string into bool",
+			script:   `value = {conditions = {{type = "Ready"}, {type = "Synced", ready = "yes"}}}`,
+			target:   &resourceStatus{},
+			path:     ".conditions[2].ready",
+			expected: "cannot unmarshal string into Go value of type bool at .conditions[2].ready",
+		},
+		{
+			name:     "fraction into integer",
+			script:   `value = {replicas = 1.5}`,
+			target:   &resourceStatus{},
+			path:     ".replicas",
+			expected: "cannot unmarshal number 1.5 into Go value of type int32 at .replicas",
+		},
+		{
+			name:     "negative into unsigned",
+			script:   `value = {uid = -1}`,
+			target:   &resourceStatus{},
+			path:     ".uid",
+			expected: "cannot unmarshal number -1 into Go value of type uint64 at .uid",
+		},
+		{
+			name:     "array into struct",
+			script:   `value = {"a"}`,
+			target:   &healthCheck{},
+			path:     "",
+			expected: "cannot unmarshal array into Go value of type json_test.healthCheck",
+		},
+		{
+			name:     "object into slice",
+			script:   `value = {conditions = {type = "Ready"}}`,
+			target:   &resourceStatus{},
+			path:     ".conditions",
+			expected: "cannot unmarshal object into Go value of type []json_test.condition",
+		},
+		{
+			name:     "invalid map key",
+			script:   `value = {ports = {http = "80"}}`,
+			target:   &resourceStatus{},
+			path:     ".ports.http",
+			expected: `cannot unmarshal key "http" into Go value of type int at .ports.http`,
+		},
+		{
+			name:     "invalid time",
+			script:   `value = {{lastTransitionTime = "yesterday"}}`,
+			target:   &[]condition{},
+			path:     "[1].lastTransitionTime",
+			expected: `at [1].lastTransitionTime`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, L.DoString(tt.script))
+
+			err := luajson.Unmarshal(L.GetGlobal("value"), tt.target)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+
+			var unmarshalErr *luajson.UnmarshalError
+			require.ErrorAs(t, err, &unmarshalErr)
+
+			assert.Equal(t, tt.path, unmarshalErr.Path)
+		})
+	}
+
+	var typeErr *luajson.UnmarshalTypeError
+
+	err := luajson.Unmarshal(lua.LString("x"), new(int))
+	require.True(t, errors.As(err, &typeErr))
+	assert.Equal(t, reflect.TypeFor[int](), typeErr.Type)
+
+	err = luajson.Unmarshal(lua.LString("x"), healthCheck{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "target must be a non-nil pointer")
+}
@@ -0,0 +1,595 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// yamlDocumentTypeName is the registry name of the metatable of YAML
+// document handles.
+const yamlDocumentTypeName = "json.yaml.document"
+
+// YAMLDocument is a parsed YAML document that keeps its comments, anchors,
+// aliases and scalar styles, so that it can be edited and rendered back with
+// its annotations intact.
+type YAMLDocument struct {
+	node    *yamlv3.Node
+	indent  int
+	compact bool
+}
+
+// ParseYAMLDocument parses the first document of the YAML encoded data.
+func ParseYAMLDocument(data []byte) (*YAMLDocument, error) {
+	var node yamlv3.Node
+
+	err := yamlv3.Unmarshal(data, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Kind == 0 {
+		node.Kind = yamlv3.DocumentNode
+	}
+
+	doc := &YAMLDocument{node: &node, indent: 2, compact: true}
+	doc.detectIndent(&node)
+
+	return doc, nil
+}
+
+// Get returns the value the RFC 6901 JSON Pointer refers to in the document.
+// Aliases are followed, and objects are marked with MarkOrdered so that they
+// keep the order of the document.
+func (d *YAMLDocument) Get(L *lua.LState, pointer string) (lua.LValue, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := d.lookup(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = writeYAMLNodeJSON(&buf, node)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeWithOptions(L, buf.Bytes(), DecodeOptions{PreserveOrder: true})
+}
+
+// Set sets the location the RFC 6901 JSON Pointer refers to in the document to
+// value. Missing intermediate mappings are created; the token "-" appends to a
+// sequence. Replacing a node keeps its comments and anchor, and a scalar of the
+// same type keeps its style.
+func (d *YAMLDocument) Set(pointer string, value lua.LValue) error {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+
+	data, err := EncodeWithOptions(value, EncodeOptions{SortKeys: true})
+	if err != nil {
+		return err
+	}
+
+	var parsed yamlv3.Node
+
+	err = yamlv3.Unmarshal(data, &parsed)
+	if err != nil {
+		return err
+	}
+
+	node := parsed.Content[0]
+	clearYAMLStyle(node)
+
+	if len(tokens) == 0 {
+		if len(d.node.Content) == 0 {
+			d.node.Content = []*yamlv3.Node{node}
+		} else {
+			replaceYAMLNode(d.node.Content[0], node)
+		}
+
+		return nil
+	}
+
+	if len(d.node.Content) == 0 {
+		d.node.Content = []*yamlv3.Node{{Kind: yamlv3.MappingNode, Tag: "!!map"}}
+	}
+
+	current := d.node.Content[0]
+
+	for i, token := range tokens[:len(tokens)-1] {
+		current = resolveYAMLAlias(current)
+
+		child, err := yamlChild(current, token, formatPointer(tokens[:i]))
+		if err != nil {
+			return err
+		}
+
+		if child == nil {
+			child = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+			yamlAppend(current, token, child)
+		}
+
+		current = child
+	}
+
+	current = resolveYAMLAlias(current)
+	token := tokens[len(tokens)-1]
+
+	child, err := yamlChild(current, token, formatPointer(tokens[:len(tokens)-1]))
+	if err != nil {
+		return err
+	}
+
+	if child == nil {
+		yamlAppend(current, token, node)
+
+		return nil
+	}
+
+	replaceYAMLNode(child, node)
+
+	return nil
+}
+
+// Remove removes the location the RFC 6901 JSON Pointer refers to from the
+// document and returns the removed value. Anchored nodes of the removed value
+// that are still aliased move in place of their first alias.
+func (d *YAMLDocument) Remove(L *lua.LState, pointer string) (lua.LValue, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New("cannot remove the document root")
+	}
+
+	removed, err := d.Get(L, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := d.lookup(tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	token := tokens[len(tokens)-1]
+
+	switch parent.Kind {
+	case yamlv3.MappingNode:
+		if i := yamlKeyIndex(parent, token); i >= 0 {
+			nodes := []*yamlv3.Node{parent.Content[i], parent.Content[i+1]}
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			d.keepAnchors(nodes)
+
+			return removed, nil
+		}
+
+		// The key comes from a merged mapping, which is shared.
+		return nil, fmt.Errorf("cannot remove merged key at %s", pointer)
+	default:
+		index, _ := arrayIndex(token, len(parent.Content), false)
+		node := parent.Content[index]
+		parent.Content = append(parent.Content[:index], parent.Content[index+1:]...)
+		d.keepAnchors([]*yamlv3.Node{node})
+
+		return removed, nil
+	}
+}
+
+// keepAnchors moves the anchored nodes of the removed nodes that aliases left
+// in the document refer to in place of the first of these aliases, so that no
+// alias is left dangling.
+func (d *YAMLDocument) keepAnchors(removed []*yamlv3.Node) {
+	anchored := make(map[*yamlv3.Node]bool)
+
+	var collect func(node *yamlv3.Node)
+
+	collect = func(node *yamlv3.Node) {
+		if node.Anchor != "" {
+			anchored[node] = true
+		}
+
+		for _, child := range node.Content {
+			collect(child)
+		}
+	}
+
+	for _, node := range removed {
+		collect(node)
+	}
+
+	if len(anchored) == 0 {
+		return
+	}
+
+	var walk func(node *yamlv3.Node)
+
+	walk = func(node *yamlv3.Node) {
+		for i, child := range node.Content {
+			if child.Kind == yamlv3.AliasNode && anchored[child.Alias] {
+				delete(anchored, child.Alias)
+				node.Content[i] = child.Alias
+				child = child.Alias
+			}
+
+			walk(child)
+		}
+	}
+
+	walk(d.node)
+}
+
+// Render returns the YAML encoding of the document. Blank lines of the parsed
+// document are not kept.
+func (d *YAMLDocument) Render() ([]byte, error) {
+	if len(d.node.Content) == 0 {
+		return []byte{}, nil
+	}
+
+	// Merge keys are rendered with an explicit !!merge tag unless the tag is
+	// left to be resolved.
+	mergeKeys := yamlMergeKeys(d.node, nil)
+	for _, key := range mergeKeys {
+		key.Tag = ""
+	}
+
+	defer func() {
+		for _, key := range mergeKeys {
+			key.Tag = yamlMergeTag
+		}
+	}()
+
+	var buf bytes.Buffer
+
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(d.indent)
+
+	if d.compact {
+		encoder.CompactSeqIndent()
+	}
+
+	err := encoder.Encode(d.node)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// yamlMergeKeys appends the merge keys found under node to keys.
+func yamlMergeKeys(node *yamlv3.Node, keys []*yamlv3.Node) []*yamlv3.Node {
+	if node.Kind == yamlv3.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			if node.Content[i].Tag == yamlMergeTag {
+				keys = append(keys, node.Content[i])
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		keys = yamlMergeKeys(child, keys)
+	}
+
+	return keys
+}
+
+// lookup returns the node tokens refer to, following aliases and merge keys.
+func (d *YAMLDocument) lookup(tokens []string) (*yamlv3.Node, error) {
+	if len(d.node.Content) == 0 {
+		if len(tokens) == 0 {
+			return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!null"}, nil
+		}
+
+		return nil, fmt.Errorf("%w at %s", errPathNotFound, formatPointer(tokens[:1]))
+	}
+
+	current := resolveYAMLAlias(d.node.Content[0])
+
+	for i, token := range tokens {
+		var child *yamlv3.Node
+
+		switch current.Kind {
+		case yamlv3.MappingNode:
+			if j := yamlKeyIndex(current, token); j >= 0 {
+				child = current.Content[j+1]
+
+				break
+			}
+
+			keys, values := yamlMappingPairs(current)
+			for j, key := range keys {
+				if key == token {
+					child = values[j]
+				}
+			}
+		case yamlv3.SequenceNode:
+			index, err := arrayIndex(token, len(current.Content), false)
+			if err == nil {
+				child = current.Content[index]
+			}
+		}
+
+		if child == nil {
+			return nil, fmt.Errorf("%w at %s", errPathNotFound, formatPointer(tokens[:i+1]))
+		}
+
+		current = resolveYAMLAlias(child)
+	}
+
+	return current, nil
+}
+
+// detectIndent sets the indentation of the document and whether sequences are
+// indented within mappings from the first nested block collections of node.
+func (d *YAMLDocument) detectIndent(node *yamlv3.Node) {
+	var foundIndent, foundCompact bool
+
+	var walk func(node *yamlv3.Node)
+
+	walk = func(node *yamlv3.Node) {
+		if foundIndent && foundCompact {
+			return
+		}
+
+		if node.Kind == yamlv3.MappingNode && node.Style&yamlv3.FlowStyle == 0 {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if value.Style&yamlv3.FlowStyle != 0 || len(value.Content) == 0 {
+					continue
+				}
+
+				switch value.Kind {
+				case yamlv3.MappingNode:
+					if !foundIndent && value.Content[0].Column > key.Column {
+						d.indent = value.Content[0].Column - key.Column
+						foundIndent = true
+					}
+				case yamlv3.SequenceNode:
+					if !foundCompact {
+						d.compact = value.Column == key.Column
+						foundCompact = true
+					}
+				}
+			}
+		}
+
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+
+	walk(node)
+}
+
+// yamlChild returns the child of node token refers to, or nil if it is missing
+// and can be appended. Keys inherited through merge keys count as missing, so
+// that setting them adds an explicit key.
+func yamlChild(node *yamlv3.Node, token, path string) (*yamlv3.Node, error) {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		if i := yamlKeyIndex(node, token); i >= 0 {
+			return node.Content[i+1], nil
+		}
+
+		return nil, nil
+	case yamlv3.SequenceNode:
+		index, err := arrayIndex(token, len(node.Content), true)
+		if err != nil {
+			return nil, err
+		}
+
+		if index == len(node.Content) {
+			return nil, nil
+		}
+
+		return node.Content[index], nil
+	default:
+		return nil, fmt.Errorf("%s is not a mapping or sequence", path)
+	}
+}
+
+// yamlKeyIndex returns the index in the mapping node of the key token refers
+// to, or -1 if there is none. Keys are matched on their text first, and only
+// then on the object key they convert to, such as "true" for a plain yes, so
+// that a y key is found by "y" rather than duplicated. Merge keys are skipped.
+func yamlKeyIndex(node *yamlv3.Node, token string) int {
+	converted := -1
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := resolveYAMLAlias(node.Content[i])
+		if key.Tag == yamlMergeTag {
+			continue
+		}
+
+		if key.Value == token {
+			return i
+		}
+
+		if converted < 0 && yamlKey(key) == token {
+			converted = i
+		}
+	}
+
+	return converted
+}
+
+// yamlAppend adds value to node under token, which yamlChild reported missing.
+func yamlAppend(node *yamlv3.Node, token string, value *yamlv3.Node) {
+	if node.Kind == yamlv3.SequenceNode {
+		node.Content = append(node.Content, value)
+
+		return
+	}
+
+	key := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: token}
+	node.Content = append(node.Content, key, value)
+}
+
+// replaceYAMLNode replaces the contents of old with node in place, so that
+// aliases of old see the new contents, keeping the comments and anchor of old.
+func replaceYAMLNode(old, node *yamlv3.Node) {
+	replaced := *old
+
+	if old.Kind == yamlv3.ScalarNode && node.Kind == yamlv3.ScalarNode && old.ShortTag() == node.ShortTag() &&
+		(old.Style != 0 || !yamlNeedsQuotes(node)) {
+		node.Style = old.Style
+	}
+
+	*old = *node
+
+	old.HeadComment = replaced.HeadComment
+	old.LineComment = replaced.LineComment
+	old.FootComment = replaced.FootComment
+
+	if replaced.Kind != yamlv3.AliasNode {
+		old.Anchor = replaced.Anchor
+	}
+}
+
+// checkYAMLDocument returns the YAML document handle at index n.
+func checkYAMLDocument(L *lua.LState, n int) *YAMLDocument {
+	ud := L.CheckUserData(n)
+
+	doc, ok := ud.Value.(*YAMLDocument)
+	if !ok {
+		L.ArgError(n, "YAML document expected")
+	}
+
+	return doc
+}
+
+func yamlDocumentMetatable(L *lua.LState) *lua.LTable {
+	mt := L.GetTypeMetatable(yamlDocumentTypeName)
+	if tbl, ok := mt.(*lua.LTable); ok {
+		return tbl
+	}
+
+	tbl := L.NewTypeMetatable(yamlDocumentTypeName)
+	tbl.RawSetString("__index", L.SetFuncs(L.NewTable(), yamlDocumentMethods))
+
+	return tbl
+}
+
+func apiParseYAMLDocument(L *lua.LState) int {
+	str := L.CheckString(1)
+
+	err := checkLimits(L, nil).checkSize(len(str))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	doc, err := ParseYAMLDocument([]byte(str))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	ud := L.NewUserData()
+	ud.Value = doc
+	ud.Metatable = yamlDocumentMetatable(L)
+
+	L.Push(ud)
+
+	return 1
+}
+
+func yamlDocumentGet(L *lua.LState) int {
+	doc := checkYAMLDocument(L, 1)
+	pointer := L.CheckString(2)
+
+	value, err := doc.Get(L, pointer)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(value)
+
+	return 1
+}
+
+func yamlDocumentSet(L *lua.LState) int {
+	doc := checkYAMLDocument(L, 1)
+	pointer := L.CheckString(2)
+	value := L.CheckAny(3)
+
+	err := doc.Set(pointer, value)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LTrue)
+
+	return 1
+}
+
+func yamlDocumentRemove(L *lua.LState) int {
+	doc := checkYAMLDocument(L, 1)
+	pointer := L.CheckString(2)
+
+	removed, err := doc.Remove(L, pointer)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(removed)
+
+	return 1
+}
+
+func yamlDocumentRender(L *lua.LState) int {
+	doc := checkYAMLDocument(L, 1)
+
+	data, err := doc.Render()
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LString(string(data)))
+
+	return 1
+}
+
+var yamlDocumentMethods = map[string]lua.LGFunction{
+	"get":    yamlDocumentGet,
+	"remove": yamlDocumentRemove,
+	"render": yamlDocumentRender,
+	"set":    yamlDocumentSet,
+}
+
+var yamlAPI = map[string]lua.LGFunction{
+	"parseDocument": apiParseYAMLDocument,
+}
@@ -0,0 +1,261 @@
+package json_test
+
+import (
+	"testing"
+
+	luajson "github.com/projectsveltos/lua-utils/glua-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestYAMLDocumentLua(t *testing.T) {
+	const str = `
+	local json = require("json")
+	assert(type(json.yaml.parseDocument) == "function")
+
+	local values = [[
+# Default values for web.
+replicaCount: 1 # scaled by the HPA
+
+image:
+  repository: nginx
+  # Overrides the chart appVersion.
+  tag: "1.25"
+
+defaults: &defaults
+  timeout: 30
+service:
+  <<: *defaults
+  port: 80
+script: |
+  echo start
+  exec nginx
+]]
+	local doc = json.yaml.parseDocument(values)
+
+	-- Test get
+	assert(doc:get("/image/repository") == "nginx")
+	assert(doc:get("/service/timeout") == 30)
+	assert(json.encode(doc:get("/image")) == '{"repository":"nginx","tag":"1.25"}')
+	assert(json.yaml.parseDocument("mode: 0644\n"):get("/mode") == 420)
+	local value, err = doc:get("/missing")
+	assert(value == nil)
+	assert(err == "path not found at /missing", err)
+
+	-- Test set and remove keep comments, anchors and styles
+	assert(doc:set("/replicaCount", 3))
+	assert(doc:set("/image/tag", "1.26"))
+	assert(doc:set("/script", "echo start\nexec nginx -g 'daemon off;'\n"))
+	assert(doc:set("/resources/limits/cpu", "500m"))
+	assert(doc:remove("/service/port") == 80)
+
+	local expected = [[
+# Default values for web.
+replicaCount: 3 # scaled by the HPA
+image:
+  repository: nginx
+  # Overrides the chart appVersion.
+  tag: "1.26"
+defaults: &defaults
+  timeout: 30
+service:
+  <<: *defaults
+script: |
+  echo start
+  exec nginx -g 'daemon off;'
+resources:
+  limits:
+    cpu: 500m
+]]
+	assert(doc:render() == expected, doc:render())
+
+	-- Test errors
+	local doc, err = json.yaml.parseDocument("invalid: yaml: content:")
+	assert(doc == nil)
+	assert(string.find(err, "yaml:"), err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luajson.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestYAMLDocumentRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		pointer  string
+		value    func(L *lua.LState) lua.LValue
+		expected string
+		wantErr  string
+	}{
+		{
+			name:    "indented sequences",
+			input:   "spec:\n    containers:\n        - name: web\n          image: nginx\n",
+			pointer: "/spec/containers/-",
+			value: func(L *lua.LState) lua.LValue {
+				tbl := L.NewTable()
+				tbl.RawSetString("name", lua.LString("sidecar"))
+
+				return tbl
+			},
+			expected: "spec:\n    containers:\n        - name: web\n          image: nginx\n        - name: sidecar\n",
+		},
+		{
+			name:    "compact sequences",
+			input:   "env:\n- name: A\n  value: a\n",
+			pointer: "/env/0/value",
+			value: func(_ *lua.LState) lua.LValue {
+				return lua.LString("true")
+			},
+			expected: "env:\n- name: A\n  value: \"true\"\n",
+		},
+		{
+			name:    "anchored node",
+			input:   "base: &base\n  a: 1\nother: *base\n",
+			pointer: "/base",
+			value: func(L *lua.LState) lua.LValue {
+				tbl := L.NewTable()
+				tbl.RawSetString("b", lua.LNumber(2))
+
+				return tbl
+			},
+			expected: "base: &base\n  b: 2\nother: *base\n",
+		},
+		{
+			name:    "empty document",
+			input:   "",
+			pointer: "/a/b",
+			value: func(_ *lua.LState) lua.LValue {
+				return lua.LNumber(1)
+			},
+			expected: "a:\n  b: 1\n",
+		},
+		{
+			name:    "scalar parent",
+			input:   "a: 1\n",
+			pointer: "/a/b",
+			value: func(_ *lua.LState) lua.LValue {
+				return lua.LNumber(1)
+			},
+			wantErr: "/a is not a mapping or sequence",
+		},
+		{
+			name:    "index out of range",
+			input:   "a: [1]\n",
+			pointer: "/a/3",
+			value: func(_ *lua.LState) lua.LValue {
+				return lua.LNumber(1)
+			},
+			wantErr: "out of range",
+		},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := luajson.ParseYAMLDocument([]byte(tt.input))
+			require.NoError(t, err)
+
+			err = doc.Set(tt.pointer, tt.value(L))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			data, err := doc.Render()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestYAMLDocumentRemove(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	doc, err := luajson.ParseYAMLDocument([]byte("list:\n- a # first\n- b\nbase: &b {x: 1}\nmerged:\n  <<: *b\n"))
+	require.NoError(t, err)
+
+	removed, err := doc.Remove(L, "/list/0")
+	require.NoError(t, err)
+	assert.Equal(t, lua.LString("a"), removed)
+
+	_, err = doc.Remove(L, "/merged/x")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot remove merged key")
+
+	_, err = doc.Remove(L, "")
+	require.Error(t, err)
+
+	data, err := doc.Render()
+	require.NoError(t, err)
+	assert.Equal(t, "list:\n- b\nbase: &b {x: 1}\nmerged:\n  <<: *b\n", string(data))
+}
+
+func TestYAMLDocumentRemoveAnchored(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	doc, err := luajson.ParseYAMLDocument([]byte("defaults: &defaults\n  timeout: 30\nweb:\n  <<: *defaults\n  port: 80\napi:\n  <<: *defaults\nlist:\n- &item a\n- *item\n"))
+	require.NoError(t, err)
+
+	_, err = doc.Remove(L, "/defaults")
+	require.NoError(t, err)
+
+	_, err = doc.Remove(L, "/list/0")
+	require.NoError(t, err)
+
+	data, err := doc.Render()
+	require.NoError(t, err)
+	assert.Equal(t, "web:\n  <<: &defaults\n    timeout: 30\n  port: 80\napi:\n  <<: *defaults\nlist:\n- &item a\n", string(data))
+
+	reparsed, err := luajson.FromYAML(L, data)
+	require.NoError(t, err)
+
+	encoded, err := luajson.Encode(reparsed)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"web":{"timeout":30,"port":80},"api":{"timeout":30},"list":["a"]}`, string(encoded))
+}
+
+func TestYAMLDocumentBooleanStrings(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	doc, err := luajson.ParseYAMLDocument([]byte("y: 1\nyes: 2\nother:\n  flag: x\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, doc.Set("/y", lua.LNumber(3)))
+	require.NoError(t, doc.Set("/other/flag", lua.LString("on")))
+	require.NoError(t, doc.Set("/other/new", lua.LString("no")))
+
+	value, err := doc.Get(L, "/other/flag")
+	require.NoError(t, err)
+	assert.Equal(t, lua.LString("on"), value)
+
+	value, err = doc.Get(L, "/y")
+	require.NoError(t, err)
+	assert.Equal(t, lua.LNumber(3), value)
+
+	value, err = doc.Get(L, "/true")
+	require.NoError(t, err)
+	assert.Equal(t, lua.LNumber(3), value)
+
+	_, err = doc.Remove(L, "/yes")
+	require.NoError(t, err)
+
+	data, err := doc.Render()
+	require.NoError(t, err)
+	assert.Equal(t, "y: 3\nother:\n  flag: \"on\"\n  new: \"no\"\n", string(data))
+}
@@ -0,0 +1,67 @@
+// Package toml is a simple TOML encoder/decoder for gopher-lua, with the type
+// mapping of glua-json.
+//
+// # Documentation
+//
+// The following functions are exposed by the library:
+//
+//	decode(string[, options]):
+//	                  Decodes a TOML string into a table. Returns nil and an
+//	                  error string, naming the line and column, if the string
+//	                  could not be decoded. See below for options.
+//	encode(table):    Encodes a table with string keys into a TOML string.
+//	                  Returns nil and an error string if the table could not
+//	                  be encoded.
+//	datetime(string): Returns a datetime that encodes as a TOML datetime rather
+//	                  than a string, from its RFC 3339 representation: an offset
+//	                  datetime such as "1979-05-27T07:32:00Z", a local datetime
+//	                  ("1979-05-27T07:32:00"), a local date ("1979-05-27") or a
+//	                  local time ("07:32:00"). Returns nil and an error string if
+//	                  the string is not such a datetime.
+//
+// The following decode options are supported:
+//
+//	markContainers:   Whether decoded tables and arrays are marked so that they
+//	                  keep their type when encoded, even once emptied. The
+//	                  markers are those of glua-json's json.object and
+//	                  json.array, so json.encode honours them too. Tables
+//	                  decoded empty are always marked, so that a decoded
+//	                  document encodes back to the same TOML. Defaults to
+//	                  false.
+//	preserveDatetimes:
+//	                  Whether datetimes are decoded to datetime values, as
+//	                  returned by datetime, instead of strings. Defaults to
+//	                  false.
+//
+// The following types are supported:
+//
+//	Lua       | TOML
+//	----------+----------
+//	boolean   | boolean
+//	number    | integer: when the number is integral
+//	          | float:   otherwise
+//	string    | string
+//	datetime  | offset datetime, local datetime, local date or local time
+//	table     | table:   when table is non-empty and has only string keys, or
+//	          |          is marked as an object
+//	          | array:   when table is empty and not marked as an object, or
+//	          |          has only sequential numeric keys starting from 1
+//
+// Datetimes decode to their RFC 3339 strings unless preserveDatetimes is set,
+// and tostring returns the same string for datetime values. Integers beyond
+// 2^53 lose precision, as Lua numbers are floats. For the same reason, floats
+// with an integral value do not keep their type: ratio = 1.0 decodes to 1 and
+// encodes back as ratio = 1. TOML has no null, so nil values are never decoded
+// and json.null cannot be encoded.
+//
+// # Example
+//
+// Below is an example usage of the library:
+//
+//	import (
+//	    luatoml "github.com/projectsveltos/lua-utils/glua-toml"
+//	)
+//
+//	L := lua.NewState()
+//	luatoml.Preload(L)
+package toml // import "github.com/projectsveltos/lua-utils/glua-toml"
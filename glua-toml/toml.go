@@ -0,0 +1,445 @@
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	gotoml "github.com/pelletier/go-toml/v2"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// typeField is the metatable field marking a table as an object (a TOML
+// table) or an array. It is shared with glua-json, so that tables marked by
+// either module keep their type in both.
+const typeField = "__jsontype"
+
+const (
+	typeObject = "object"
+	typeArray  = "array"
+)
+
+// datetimeTypeName is the registry name of the metatable of datetimes.
+const datetimeTypeName = "toml.datetime"
+
+var (
+	errNested      = errors.New("cannot encode recursively nested tables to TOML")
+	errSparseArray = errors.New("cannot encode sparse array")
+	errInvalidKeys = errors.New("cannot encode mixed or invalid key types")
+	errNotTable    = errors.New("cannot encode TOML document: value must be a table with string keys")
+)
+
+type invalidTypeError lua.LValueType
+
+func (i invalidTypeError) Error() string {
+	return `cannot encode ` + lua.LValueType(i).String() + ` to TOML`
+}
+
+// Datetime is a TOML offset datetime, local datetime, local date or local
+// time.
+type Datetime struct {
+	value any
+}
+
+// ParseDatetime parses text as an RFC 3339 datetime with offset, such as
+// "1979-05-27T07:32:00Z", a local datetime ("1979-05-27T07:32:00"), a local
+// date ("1979-05-27") or a local time ("07:32:00").
+func ParseDatetime(text string) (Datetime, error) {
+	if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+		return Datetime{value: t}, nil
+	}
+
+	var dateTime gotoml.LocalDateTime
+	if err := dateTime.UnmarshalText([]byte(text)); err == nil {
+		return Datetime{value: dateTime}, nil
+	}
+
+	var date gotoml.LocalDate
+	if err := date.UnmarshalText([]byte(text)); err == nil {
+		return Datetime{value: date}, nil
+	}
+
+	var localTime gotoml.LocalTime
+	if err := localTime.UnmarshalText([]byte(text)); err == nil {
+		return Datetime{value: localTime}, nil
+	}
+
+	return Datetime{}, fmt.Errorf("invalid TOML datetime %q", text)
+}
+
+// String returns the RFC 3339 representation of d.
+func (d Datetime) String() string {
+	if t, ok := d.value.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(d.value)
+}
+
+// DecodeOptions controls how decoded values are converted to Lua values.
+type DecodeOptions struct {
+	// MarkContainers marks decoded tables and arrays with a metatable holding
+	// a __jsontype field of "object" or "array", so that they keep their type
+	// when encoded, even once emptied. Tables decoded empty are always
+	// marked as objects.
+	MarkContainers bool
+	// PreserveDatetimes decodes datetimes to Datetime userdata, which encode
+	// back to TOML datetimes, instead of to their RFC 3339 strings.
+	PreserveDatetimes bool
+}
+
+// Decode converts the TOML encoded data to Lua values.
+func Decode(L *lua.LState, data []byte) (lua.LValue, error) {
+	return DecodeWithOptions(L, data, DecodeOptions{})
+}
+
+// DecodeWithOptions is like Decode, converting values according to opts.
+func DecodeWithOptions(L *lua.LState, data []byte, opts DecodeOptions) (lua.LValue, error) {
+	var doc map[string]any
+
+	err := gotoml.Unmarshal(data, &doc)
+	if err != nil {
+		var decodeErr *gotoml.DecodeError
+		if errors.As(err, &decodeErr) {
+			row, column := decodeErr.Position()
+
+			return nil, fmt.Errorf("%w at line %d, column %d", err, row, column)
+		}
+
+		return nil, err
+	}
+
+	if doc == nil {
+		doc = map[string]any{}
+	}
+
+	return decodeValue(L, doc, opts), nil
+}
+
+func decodeValue(L *lua.LState, value any, opts DecodeOptions) lua.LValue {
+	switch converted := value.(type) {
+	case bool:
+		return lua.LBool(converted)
+	case int64:
+		return lua.LNumber(converted)
+	case float64:
+		return lua.LNumber(converted)
+	case string:
+		return lua.LString(converted)
+	case time.Time, gotoml.LocalDateTime, gotoml.LocalDate, gotoml.LocalTime:
+		datetime := Datetime{value: converted}
+		if opts.PreserveDatetimes {
+			return newDatetime(L, datetime)
+		}
+
+		return lua.LString(datetime.String())
+	case []any:
+		arr := L.CreateTable(len(converted), 0)
+		for _, item := range converted {
+			arr.Append(decodeValue(L, item, opts))
+		}
+
+		if opts.MarkContainers {
+			arr.Metatable = markerMetatable(L, typeArray)
+		}
+
+		return arr
+	case map[string]any:
+		keys := make([]string, 0, len(converted))
+		for key := range converted {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		tbl := L.CreateTable(0, len(converted))
+		for _, key := range keys {
+			tbl.RawSetString(key, decodeValue(L, converted[key], opts))
+		}
+
+		// Empty tables are always marked, so that they stay tables when
+		// encoded again.
+		if opts.MarkContainers || len(converted) == 0 {
+			tbl.Metatable = markerMetatable(L, typeObject)
+		}
+
+		return tbl
+	default:
+		return lua.LNil
+	}
+}
+
+func markerMetatable(L *lua.LState, typ string) *lua.LTable {
+	mt := L.NewTypeMetatable("json." + typ)
+	mt.RawSetString(typeField, lua.LString(typ))
+
+	return mt
+}
+
+// tableType returns the type tbl is marked with, or the empty string if it is
+// not marked.
+func tableType(tbl *lua.LTable) string {
+	mt, ok := tbl.Metatable.(*lua.LTable)
+	if !ok {
+		return ""
+	}
+
+	switch typ := mt.RawGetString(typeField).String(); typ {
+	case typeObject, typeArray:
+		return typ
+	default:
+		return ""
+	}
+}
+
+// Encode returns the TOML encoding of value, which must be a table with
+// string keys. Tables follow the rules of glua-json: tables with only string
+// keys, and empty tables marked as objects, become TOML tables, other tables
+// arrays. Integral numbers become integers, even those decoded from floats
+// such as 1.0, and other numbers floats.
+func Encode(value lua.LValue) ([]byte, error) {
+	tbl, ok := value.(*lua.LTable)
+	if !ok {
+		return nil, errNotTable
+	}
+
+	state := &encodeState{visited: make(map[*lua.LTable]bool)}
+
+	doc, err := state.table(tbl)
+	if err != nil {
+		return nil, err
+	}
+
+	if arr, ok := doc.([]any); ok {
+		if len(arr) > 0 {
+			return nil, errNotTable
+		}
+
+		doc = map[string]any{}
+	}
+
+	return gotoml.Marshal(doc)
+}
+
+type encodeState struct {
+	visited map[*lua.LTable]bool
+}
+
+func (s *encodeState) value(value lua.LValue) (any, error) {
+	switch converted := value.(type) {
+	case lua.LBool:
+		return bool(converted), nil
+	case lua.LString:
+		return string(converted), nil
+	case lua.LNumber:
+		f := float64(converted)
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), nil
+		}
+
+		return f, nil
+	case *lua.LUserData:
+		if datetime, ok := converted.Value.(Datetime); ok {
+			return datetime.value, nil
+		}
+
+		return nil, invalidTypeError(value.Type())
+	case *lua.LTable:
+		return s.table(converted)
+	default:
+		return nil, invalidTypeError(value.Type())
+	}
+}
+
+func (s *encodeState) table(tbl *lua.LTable) (any, error) {
+	if s.visited[tbl] {
+		return nil, errNested
+	}
+
+	s.visited[tbl] = true
+	defer delete(s.visited, tbl)
+
+	var (
+		stringKeys int
+		maxIndex   int
+		count      int
+	)
+
+	for key, _ := tbl.Next(lua.LNil); key != lua.LNil; key, _ = tbl.Next(key) {
+		count++
+
+		switch converted := key.(type) {
+		case lua.LString:
+			stringKeys++
+		case lua.LNumber:
+			index := int(converted)
+			if lua.LNumber(index) != converted || index < 1 {
+				return nil, errInvalidKeys
+			}
+
+			maxIndex = max(maxIndex, index)
+		default:
+			return nil, errInvalidKeys
+		}
+	}
+
+	typ := tableType(tbl)
+
+	switch {
+	case stringKeys > 0 && stringKeys < count:
+		return nil, errInvalidKeys
+	case stringKeys > 0 || (count == 0 && typ == typeObject):
+		if typ == typeArray {
+			return nil, errInvalidKeys
+		}
+
+		obj := make(map[string]any, count)
+
+		for key, item := tbl.Next(lua.LNil); key != lua.LNil; key, item = tbl.Next(key) {
+			value, err := s.value(item)
+			if err != nil {
+				return nil, err
+			}
+
+			obj[string(key.(lua.LString))] = value
+		}
+
+		return obj, nil
+	case typ == typeObject:
+		return nil, errInvalidKeys
+	case maxIndex != count:
+		return nil, errSparseArray
+	}
+
+	arr := make([]any, count)
+
+	for i := range arr {
+		value, err := s.value(tbl.RawGetInt(i + 1))
+		if err != nil {
+			return nil, err
+		}
+
+		arr[i] = value
+	}
+
+	return arr, nil
+}
+
+func newDatetime(L *lua.LState, datetime Datetime) *lua.LUserData {
+	ud := L.NewUserData()
+	ud.Value = datetime
+	ud.Metatable = datetimeMetatable(L)
+
+	return ud
+}
+
+func datetimeMetatable(L *lua.LState) *lua.LTable {
+	mt := L.GetTypeMetatable(datetimeTypeName)
+	if tbl, ok := mt.(*lua.LTable); ok {
+		return tbl
+	}
+
+	tbl := L.NewTypeMetatable(datetimeTypeName)
+	L.SetFuncs(tbl, map[string]lua.LGFunction{
+		"__tostring": datetimeToString,
+		"__eq":       datetimeEqual,
+	})
+
+	return tbl
+}
+
+func datetimeToString(L *lua.LState) int {
+	datetime, _ := L.CheckUserData(1).Value.(Datetime)
+
+	L.Push(lua.LString(datetime.String()))
+
+	return 1
+}
+
+func datetimeEqual(L *lua.LState) int {
+	a, _ := L.CheckUserData(1).Value.(Datetime)
+	b, _ := L.CheckUserData(2).Value.(Datetime)
+
+	L.Push(lua.LBool(a.String() == b.String()))
+
+	return 1
+}
+
+func apiDecode(L *lua.LState) int {
+	str := L.CheckString(1)
+
+	var opts DecodeOptions
+	if tbl := L.OptTable(2, nil); tbl != nil {
+		opts.MarkContainers = lua.LVAsBool(tbl.RawGetString("markContainers"))
+		opts.PreserveDatetimes = lua.LVAsBool(tbl.RawGetString("preserveDatetimes"))
+	}
+
+	value, err := DecodeWithOptions(L, []byte(str), opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(value)
+
+	return 1
+}
+
+func apiEncode(L *lua.LState) int {
+	value := L.CheckAny(1)
+
+	data, err := Encode(value)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(lua.LString(string(data)))
+
+	return 1
+}
+
+func apiDatetime(L *lua.LState) int {
+	str := L.CheckString(1)
+
+	datetime, err := ParseDatetime(str)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	L.Push(newDatetime(L, datetime))
+
+	return 1
+}
+
+var api = map[string]lua.LGFunction{
+	"datetime": apiDatetime,
+	"decode":   apiDecode,
+	"encode":   apiEncode,
+}
+
+// Loader is the module loader function.
+func Loader(L *lua.LState) int {
+	t := L.NewTable()
+	L.SetFuncs(t, api)
+	L.Push(t)
+
+	return 1
+}
+
+// Preload adds toml to the given Lua state's package.preload table. After it
+// has been preloaded, it can be loaded using require:
+//
+//	local toml = require("toml")
+func Preload(L *lua.LState) {
+	L.PreloadModule("toml", Loader)
+}
@@ -0,0 +1,181 @@
+package toml_test
+
+import (
+	"testing"
+
+	luatoml "github.com/projectsveltos/lua-utils/glua-toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestTOMLLua(t *testing.T) {
+	const str = `
+	local toml = require("toml")
+	assert(type(toml.decode) == "function")
+	assert(type(toml.encode) == "function")
+	assert(type(toml.datetime) == "function")
+
+	-- Test decoding a containerd configuration
+	local config = toml.decode([==[
+version = 2
+root = "/var/lib/containerd"
+
+[grpc]
+  address = "/run/containerd/containerd.sock"
+  max_recv_message_size = 16777216
+
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  default_runtime_name = "runc"
+  snapshotter = "overlayfs"
+
+[[mirrors]]
+  host = "docker.io"
+  endpoints = ["https://mirror.example.com", "https://registry-1.docker.io"]
+
+[[mirrors]]
+  host = "quay.io"
+  ratio = 0.5
+]==])
+	assert(config.version == 2)
+	assert(config.grpc.max_recv_message_size == 16777216)
+	assert(config.plugins["io.containerd.grpc.v1.cri"].containerd.snapshotter == "overlayfs")
+	assert(#config.mirrors == 2)
+	assert(config.mirrors[1].endpoints[2] == "https://registry-1.docker.io")
+	assert(config.mirrors[2].ratio == 0.5)
+
+	-- Test the round trip
+	config.grpc.address = "/run/k3s/containerd/containerd.sock"
+	local again = toml.decode(toml.encode(config))
+	assert(again.grpc.address == "/run/k3s/containerd/containerd.sock")
+	assert(again.mirrors[2].host == "quay.io")
+
+	-- Test integral floats, which become integers
+	assert(toml.encode(toml.decode("ratio = 1.0\n")) == "ratio = 1\n")
+
+	-- Test datetimes
+	local release = toml.decode('date = 1979-05-27T07:32:00-08:00\nday = 1979-05-27\n')
+	assert(release.date == "1979-05-27T07:32:00-08:00", release.date)
+	assert(release.day == "1979-05-27")
+
+	local release = toml.decode('date = 1979-05-27T07:32:00Z\nat = 07:32:00\n', {preserveDatetimes = true})
+	assert(type(release.date) == "userdata")
+	assert(tostring(release.date) == "1979-05-27T07:32:00Z")
+	assert(release.date == toml.datetime("1979-05-27T07:32:00Z"))
+	assert(tostring(release.at) == "07:32:00")
+	assert(toml.encode(release) == "at = 07:32:00\ndate = 1979-05-27T07:32:00Z\n", toml.encode(release))
+	assert(toml.encode({day = toml.datetime("2024-01-02")}) == "day = 2024-01-02\n")
+
+	-- Test options
+	local empty = toml.decode("[server]\n", {markContainers = true})
+	assert(toml.encode(empty) == "[server]\n", toml.encode(empty))
+	assert(toml.encode(toml.decode("[server]\n")) == "[server]\n")
+	local doc = toml.decode("name = 'web'\n[empty]\n[server.tls]\n")
+	assert(toml.encode(doc) == "name = 'web'\n\n[empty]\n\n[server]\n[server.tls]\n", toml.encode(doc))
+	assert(toml.encode({server = {}}) == "server = []\n")
+
+	-- Test errors
+	local value, err = toml.decode("a = \n")
+	assert(value == nil)
+	assert(string.find(err, "at line 1, column 5"), err)
+
+	local value, err = toml.datetime("yesterday")
+	assert(value == nil)
+	assert(err == 'invalid TOML datetime "yesterday"', err)
+
+	local out, err = toml.encode({1, 2})
+	assert(out == nil)
+	assert(err == "cannot encode TOML document: value must be a table with string keys", err)
+
+	local out, err = toml.encode({a = {1, nil, 3}})
+	assert(out == nil)
+	assert(err == "cannot encode sparse array", err)
+
+	local out, err = toml.encode({a = function() end})
+	assert(out == nil)
+	assert(err == "cannot encode function to TOML", err)`
+
+	s := lua.NewState()
+	defer s.Close()
+
+	luatoml.Preload(s)
+
+	if err := s.DoString(str); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "scalars",
+			script:   `return {name = "web", replicas = 3, ratio = 0.25, enabled = true}`,
+			expected: "enabled = true\nname = 'web'\nratio = 0.25\nreplicas = 3\n",
+		},
+		{
+			name:     "empty document",
+			script:   `return {}`,
+			expected: "",
+		},
+		{
+			name:     "nested tables",
+			script:   `return {server = {tls = {enabled = false}}}`,
+			expected: "[server]\n[server.tls]\nenabled = false\n",
+		},
+		{
+			name:    "mixed keys",
+			script:  `return {a = {1, b = 2}}`,
+			wantErr: "cannot encode mixed or invalid key types",
+		},
+		{
+			name:    "nested",
+			script:  `local t = {} t.self = t return t`,
+			wantErr: "cannot encode recursively nested tables to TOML",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, L.DoString(tt.script))
+
+			value := L.Get(-1)
+			L.Pop(1)
+
+			data, err := luatoml.Encode(value)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestParseDatetime(t *testing.T) {
+	for _, text := range []string{
+		"1979-05-27T07:32:00Z",
+		"1979-05-27T00:32:00.999999-07:00",
+		"1979-05-27T07:32:00",
+		"1979-05-27",
+		"07:32:00",
+	} {
+		datetime, err := luatoml.ParseDatetime(text)
+		require.NoError(t, err)
+		assert.Equal(t, text, datetime.String())
+	}
+
+	_, err := luatoml.ParseDatetime("1979-13-27")
+	require.Error(t, err)
+}